@@ -0,0 +1,82 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultCloseTimeout 单个资源释放函数的默认超时时间
+const DefaultCloseTimeout = 5 * time.Second
+
+// CloserFunc 资源释放函数, ctx 到期后应尽快返回
+type CloserFunc func(ctx context.Context) error
+
+type closer struct {
+	name    string
+	fn      CloserFunc
+	timeout time.Duration
+}
+
+var (
+	closerMu   sync.Mutex
+	closerList []closer
+)
+
+// RegisterCloser 注册程序退出时执行的资源释放函数, 和 defer 一样按注册顺序的逆序执行
+func RegisterCloser(name string, fn CloserFunc, timeout ...time.Duration) {
+	c := closer{name: name, fn: fn, timeout: DefaultCloseTimeout}
+	if len(timeout) > 0 && timeout[0] > 0 {
+		c.timeout = timeout[0]
+	}
+
+	closerMu.Lock()
+	defer closerMu.Unlock()
+	closerList = append(closerList, c)
+}
+
+// RunClosers 依次执行已注册的资源释放函数, 单个函数失败或超时只记录日志, 不影响后续的执行
+func RunClosers() error {
+	closerMu.Lock()
+	list := closerList
+	closerList = nil
+	closerMu.Unlock()
+
+	var errs []error
+	for i := len(list) - 1; i >= 0; i-- {
+		if err := list[i].run(); err != nil {
+			Echo.Errorf("❌  错误: %s", err)
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (self closer) run() (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), self.timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- self.fn(ctx)
+	}()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("超过 %s 未完成", self.timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("`%s` 释放失败: %w", self.name, err)
+	}
+
+	return nil
+}
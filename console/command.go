@@ -1,6 +1,7 @@
 package console
 
 import (
+	"slices"
 	"strings"
 
 	"github.com/soryetong/greasyx/ginahelper"
@@ -22,23 +23,15 @@ var RootCmd = &cobra.Command{
 	Short: "go gin frame",
 	Long:  `Web project scaffolding based on go+gin framework`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if mapCommand["Gina"] == nil {
+		if moduleMap["Gina"] == nil {
 			Echo.Fatalw("❌ 错误: 请务必在入口函数 `main()` 中通过 `_ github.com/soryetong/greasyx/gina` 加载Greasyx模块")
 		}
-		// 确保 Gina 命令最先执行
-		mapCommand["Gina"](cmd, args)
-
-		for name, runFunc := range mapCommand {
-			name = strings.ToUpper(name)
-			if name == "START" || name == "GINA" || name == "AUTOC" || name == "CASBIN" {
-				continue
-			}
-			runFunc(cmd, args)
-		}
 
-		// Casbin 有依赖项,  所以需要放在后面执行
-		if mapCommand["Casbin"] != nil {
-			mapCommand["Casbin"](cmd, args)
+		appendCommandModules(cmd, args)
+
+		// 按照模块声明的依赖关系依次加载
+		if err := InitModules(); err != nil {
+			Echo.Fatalf("❌ 错误: %s\n", err)
 		}
 
 		// 确保 Start 命令最后执行
@@ -52,3 +45,23 @@ func Append(cmdList ...*cobra.Command) {
 		mapCommand[cmd.Use] = cmd.Run
 	}
 }
+
+// 将只通过 Append 注册的命令转换为模块, 和其他模块一起按依赖顺序加载
+func appendCommandModules(cmd *cobra.Command, args []string) {
+	names := make([]string, 0, len(mapCommand))
+	for name := range mapCommand {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		upperName := strings.ToUpper(name)
+		if upperName == "START" || upperName == "AUTOC" || moduleMap[name] != nil {
+			continue
+		}
+
+		run := mapCommand[name]
+		moduleMap[name] = &commandModule{name: name, run: func() { run(cmd, args) }}
+		moduleNames = append(moduleNames, name)
+	}
+}
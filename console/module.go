@@ -0,0 +1,171 @@
+package console
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// IModule 模块接口, 启动时按照 DependsOn 声明的依赖关系顺序加载
+type IModule interface {
+	// Name 模块名称, 同时也是模块对应的命令名称
+	Name() string
+	// DependsOn 依赖的模块名称, 被依赖的模块会先于当前模块加载
+	DependsOn() []string
+	// Init 加载模块
+	Init() error
+	// Close 释放模块占用的资源, 和模块加载时通过 RegisterCloser 注册的函数一起按逆序执行
+	Close() error
+}
+
+var (
+	moduleMap     = make(map[string]IModule)
+	moduleNames   []string
+	loadedModules []IModule
+	loadedMap     = make(map[string]bool)
+)
+
+// AppendModule 注册模块及其对应的命令, 单独执行该命令时会先加载它依赖的模块
+func AppendModule(module IModule, cmd *cobra.Command) {
+	name := module.Name()
+	if _, exists := moduleMap[name]; exists {
+		Echo.Fatalf("❌ 错误: 模块 `%s` 重复注册\n", name)
+	}
+	moduleMap[name] = module
+	moduleNames = append(moduleNames, name)
+
+	if cmd == nil {
+		cmd = &cobra.Command{Short: "Init " + name}
+	}
+	cmd.Use = name
+	cmd.Run = func(cmd *cobra.Command, args []string) {
+		if err := InitModules(name); err != nil {
+			Echo.Fatalf("❌ 错误: %s\n", err)
+		}
+	}
+	RootCmd.AddCommand(cmd)
+}
+
+// GetModule 获取已注册的模块
+func GetModule(name string) IModule {
+	return moduleMap[name]
+}
+
+// LoadedModules 获取已加载的模块, 按加载顺序排列
+func LoadedModules() []IModule {
+	return slices.Clone(loadedModules)
+}
+
+// InitModules 按依赖顺序加载指定的模块, 不指定时加载所有已注册的模块, 已加载的模块不会重复加载
+func InitModules(names ...string) error {
+	ordered, err := ResolveModules(names...)
+	if err != nil {
+		return err
+	}
+
+	for _, module := range ordered {
+		if loadedMap[module.Name()] {
+			continue
+		}
+		if err = module.Init(); err != nil {
+			return fmt.Errorf("模块 `%s` 加载失败: %w", module.Name(), err)
+		}
+		loadedModules = append(loadedModules, module)
+		loadedMap[module.Name()] = true
+		RegisterCloser("module:"+module.Name(), func(ctx context.Context) error {
+			return module.Close()
+		})
+	}
+
+	return nil
+}
+
+// CloseModules 按加载顺序的逆序关闭已加载的模块, 并释放所有通过 RegisterCloser 注册的资源
+func CloseModules() error {
+	err := RunClosers()
+	loadedModules = nil
+	loadedMap = make(map[string]bool)
+
+	return err
+}
+
+// ResolveModules 按依赖关系对模块进行拓扑排序, 依赖缺失或存在循环依赖时返回错误
+func ResolveModules(names ...string) ([]IModule, error) {
+	if len(names) == 0 {
+		names = moduleNames
+	}
+
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make(map[string]int)
+	var ordered []IModule
+	var path []string
+
+	var visit func(name, from string) error
+	visit = func(name, from string) error {
+		module, ok := moduleMap[name]
+		if !ok {
+			if from == "" {
+				return fmt.Errorf("模块 `%s` 未注册", name)
+			}
+			return fmt.Errorf("模块 `%s` 依赖的模块 `%s` 未注册, 请确保已加载该模块", from, name)
+		}
+
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			cycle := append(slices.Clone(path[slices.Index(path, name):]), name)
+			return fmt.Errorf("模块之间存在循环依赖: %s", strings.Join(cycle, " -> "))
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range module.DependsOn() {
+			if err := visit(dep, name); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		ordered = append(ordered, module)
+
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
+
+// commandModule 兼容只通过 Append 注册命令的旧模块, 默认依赖 Gina
+type commandModule struct {
+	name string
+	run  func()
+}
+
+func (self *commandModule) Name() string {
+	return self.name
+}
+
+func (self *commandModule) DependsOn() []string {
+	return []string{"Gina"}
+}
+
+func (self *commandModule) Init() error {
+	self.run()
+	return nil
+}
+
+func (self *commandModule) Close() error {
+	return nil
+}
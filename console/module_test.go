@@ -0,0 +1,199 @@
+package console
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type fakeModule struct {
+	name    string
+	deps    []string
+	initErr error
+	events  *[]string
+}
+
+func (self *fakeModule) Name() string {
+	return self.name
+}
+
+func (self *fakeModule) DependsOn() []string {
+	return self.deps
+}
+
+func (self *fakeModule) Init() error {
+	*self.events = append(*self.events, "init:"+self.name)
+	return self.initErr
+}
+
+func (self *fakeModule) Close() error {
+	*self.events = append(*self.events, "close:"+self.name)
+	return nil
+}
+
+// 使用 modules 替换已注册的模块, 测试结束后恢复, 不会添加命令
+func useModules(t *testing.T, modules ...*fakeModule) {
+	t.Helper()
+	oldMap, oldNames := moduleMap, moduleNames
+	moduleMap, moduleNames = make(map[string]IModule), nil
+	for _, module := range modules {
+		moduleMap[module.name] = module
+		moduleNames = append(moduleNames, module.name)
+	}
+	t.Cleanup(func() {
+		moduleMap, moduleNames = oldMap, oldNames
+		loadedModules, loadedMap = nil, make(map[string]bool)
+	})
+}
+
+func names(modules []IModule) []string {
+	list := make([]string, len(modules))
+	for i, module := range modules {
+		list[i] = module.Name()
+	}
+
+	return list
+}
+
+func TestResolveModules(t *testing.T) {
+	// name => 依赖
+	type graph map[string][]string
+	tests := []struct {
+		name    string
+		graph   graph
+		order   []string // 注册顺序, 为空时按名称排序
+		resolve []string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:  "按依赖排序",
+			graph: graph{"Gina": nil, "db": {"Gina"}, "Casbin": {"Gina", "db"}},
+			order: []string{"Casbin", "db", "Gina"},
+			want:  []string{"Gina", "db", "Casbin"},
+		},
+		{
+			name:  "没有依赖关系时保持注册顺序",
+			graph: graph{"Gina": nil, "Redis": {"Gina"}, "db": {"Gina"}},
+			order: []string{"Gina", "Redis", "db"},
+			want:  []string{"Gina", "Redis", "db"},
+		},
+		{
+			name:    "只加载指定的模块及其依赖",
+			graph:   graph{"Gina": nil, "Redis": {"Gina"}, "Jobs": {"Redis"}, "db": {"Gina"}},
+			resolve: []string{"Jobs"},
+			want:    []string{"Gina", "Redis", "Jobs"},
+		},
+		{
+			name:  "共同的依赖只出现一次",
+			graph: graph{"Gina": nil, "Redis": {"Gina"}, "Jobs": {"Redis", "Gina"}, "Cron": {"Redis"}},
+			order: []string{"Jobs", "Cron", "Redis", "Gina"},
+			want:  []string{"Gina", "Redis", "Jobs", "Cron"},
+		},
+		{
+			name:    "依赖的模块未注册",
+			graph:   graph{"Casbin": {"db"}},
+			wantErr: "模块 `Casbin` 依赖的模块 `db` 未注册",
+		},
+		{
+			name:    "指定的模块未注册",
+			graph:   graph{"Gina": nil},
+			resolve: []string{"Mongo"},
+			wantErr: "模块 `Mongo` 未注册",
+		},
+		{
+			name:    "循环依赖",
+			graph:   graph{"Gina": nil, "a": {"Gina", "b"}, "b": {"c"}, "c": {"a"}},
+			order:   []string{"Gina", "a", "b", "c"},
+			wantErr: "a -> b -> c -> a",
+		},
+		{
+			name:    "依赖自身",
+			graph:   graph{"a": {"a"}},
+			wantErr: "a -> a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := tt.order
+			if order == nil {
+				order = slices.Sorted(maps.Keys(tt.graph))
+			}
+			var events []string
+			modules := make([]*fakeModule, 0, len(order))
+			for _, name := range order {
+				modules = append(modules, &fakeModule{name: name, deps: tt.graph[name], events: &events})
+			}
+			useModules(t, modules...)
+
+			resolved, err := ResolveModules(tt.resolve...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("错误为 %v, 应该包含 %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(resolved); !slices.Equal(got, tt.want) {
+				t.Fatalf("加载顺序为 %v, 应该为 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 已加载的模块不会重复加载, 关闭时按加载顺序的逆序
+func TestInitAndCloseModules(t *testing.T) {
+	var events []string
+	useModules(t,
+		&fakeModule{name: "Gina", events: &events},
+		&fakeModule{name: "Redis", deps: []string{"Gina"}, events: &events},
+		&fakeModule{name: "Jobs", deps: []string{"Redis"}, events: &events},
+	)
+
+	if err := InitModules("Redis"); err != nil {
+		t.Fatal(err)
+	}
+	if err := InitModules(); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(LoadedModules()); !slices.Equal(got, []string{"Gina", "Redis", "Jobs"}) {
+		t.Fatalf("已加载的模块为 %v", got)
+	}
+	if err := CloseModules(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"init:Gina", "init:Redis", "init:Jobs", "close:Jobs", "close:Redis", "close:Gina"}
+	if !slices.Equal(events, want) {
+		t.Fatalf("执行顺序为 %v, 应该为 %v", events, want)
+	}
+	if len(LoadedModules()) != 0 {
+		t.Fatal("关闭后应该清空已加载的模块")
+	}
+}
+
+// 加载失败时停止加载后续的模块, 已加载的模块仍然可以关闭
+func TestInitModulesError(t *testing.T) {
+	var events []string
+	boom := errors.New("boom")
+	useModules(t,
+		&fakeModule{name: "Gina", events: &events},
+		&fakeModule{name: "db", deps: []string{"Gina"}, initErr: boom, events: &events},
+		&fakeModule{name: "Casbin", deps: []string{"db"}, events: &events},
+	)
+
+	err := InitModules()
+	if !errors.Is(err, boom) || !strings.Contains(err.Error(), "模块 `db` 加载失败") {
+		t.Fatalf("错误为 %v", err)
+	}
+	_ = CloseModules()
+
+	want := []string{"init:Gina", "init:db", "close:Gina"}
+	if !slices.Equal(events, want) {
+		t.Fatalf("执行顺序为 %v, 应该为 %v", events, want)
+	}
+}
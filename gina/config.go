@@ -0,0 +1,168 @@
+package gina
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/soryetong/greasyx/console"
+	"github.com/soryetong/greasyx/ginahelper"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// 配置文件变化后等待的时间, 避免编辑器多次写入导致重复加载
+const configDebounce = 500 * time.Millisecond
+
+type configSubscriber struct {
+	section  string
+	decode   func(v *viper.Viper) (any, error)
+	validate func(value any) error
+	apply    func(old, new any)
+}
+
+var (
+	configMu       sync.Mutex
+	configSubs     []*configSubscriber
+	configSettings map[string]any // 最近一次生效的配置文件内容, 不包含默认值
+	configTimer    *time.Timer
+)
+
+// OnConfigChange 订阅配置节点的变化, 节点内容按 T 解析, 只有内容发生变化时才会回调
+// validate 用于校验新的配置, 任一订阅者校验失败时, 本次变更整体不生效
+// 注意: 回调中的值只包含配置文件中的内容, 不包含通过 viper.SetDefault 设置的默认值
+func OnConfigChange[T any](section string, fn func(old, new T), validate ...func(new T) error) {
+	sub := &configSubscriber{
+		section: section,
+		decode: func(v *viper.Viper) (any, error) {
+			var value T
+			err := v.UnmarshalKey(section, &value)
+			return value, err
+		},
+		validate: func(value any) error {
+			for _, check := range validate {
+				if err := check(value.(T)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		apply: func(old, new any) {
+			fn(old.(T), new.(T))
+		},
+	}
+
+	configMu.Lock()
+	defer configMu.Unlock()
+	configSubs = append(configSubs, sub)
+}
+
+// 监听参与合并的所有配置文件, 变化后校验并通知订阅者
+func watchConfig() {
+	for _, file := range configFiles {
+		watcher := viper.New()
+		watcher.SetConfigFile(file)
+		if err := watcher.ReadInConfig(); err != nil {
+			console.Echo.Errorf("❌ 错误: 监听配置文件失败: %s", err)
+			continue
+		}
+
+		watcher.OnConfigChange(func(e fsnotify.Event) {
+			configMu.Lock()
+			defer configMu.Unlock()
+			if configTimer != nil {
+				configTimer.Stop()
+			}
+			configTimer = time.AfterFunc(configDebounce, func() {
+				ginahelper.RunSafe(reloadConfig)
+			})
+		})
+		watcher.WatchConfig()
+		console.Echo.Infof("✅ 提示: 已开启配置热更新, 监听文件: %s\n", file)
+	}
+}
+
+func stopWatchConfig() {
+	configMu.Lock()
+	defer configMu.Unlock()
+	if configTimer != nil {
+		configTimer.Stop()
+	}
+}
+
+// 重新读取配置文件, 所有订阅者校验通过后才会替换当前配置并回调
+func reloadConfig() {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	settings, _, err := loadConfig(configFile)
+	if err != nil {
+		Log.Error("[Config.Reload] 读取配置文件失败, 本次变更不生效", zap.Error(err))
+		return
+	}
+	newViper := viper.New()
+	_ = newViper.MergeConfigMap(settings)
+
+	oldViper := viper.New()
+	_ = oldViper.MergeConfigMap(configSettings)
+
+	type change struct {
+		sub      *configSubscriber
+		old, new any
+	}
+	var changes []change
+	var errs []error
+	for _, sub := range configSubs {
+		old, _ := sub.decode(oldViper)
+		value, err := sub.decode(newViper)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sub.section, err))
+			continue
+		}
+		if reflect.DeepEqual(old, value) {
+			continue
+		}
+		if err = sub.validate(value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sub.section, err))
+			continue
+		}
+		changes = append(changes, change{sub: sub, old: old, new: value})
+	}
+
+	if len(errs) > 0 {
+		Log.Error("[Config.Reload] 配置校验失败, 本次变更不生效", zap.Error(errors.Join(errs...)))
+		return
+	}
+
+	if err = replaceConfig(settings); err != nil {
+		Log.Error("[Config.Reload] 替换配置失败, 本次变更不生效", zap.Error(err))
+		return
+	}
+	configSettings = settings
+
+	sections := make([]string, 0, len(changes))
+	for _, c := range changes {
+		sections = append(sections, c.sub.section)
+		ginahelper.RunSafe(func() {
+			c.sub.apply(c.old, c.new)
+		})
+	}
+	console.Echo.Infof("✅ 提示: 配置热更新成功, 变化的配置: %s\n", strings.Join(sections, ","))
+}
+
+// 用新的配置文件内容替换 viper 中的配置, 默认值和通过 viper.Set 设置的值不受影响
+func replaceConfig(settings map[string]any) error {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+
+	viper.SetConfigType("json")
+	return viper.ReadConfig(bytes.NewReader(data))
+}
@@ -0,0 +1,249 @@
+package gina
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/soryetong/greasyx/console"
+	"github.com/soryetong/greasyx/ginahelper"
+	"github.com/spf13/cobra"
+)
+
+// 配置项的类型
+const (
+	ConfigTypeString = "string"
+	ConfigTypeInt    = "int"
+	ConfigTypeFloat  = "float"
+	ConfigTypeBool   = "bool"
+	ConfigTypeArray  = "array"
+	ConfigTypeMap    = "map"
+	// 时间, 如 "30s"、"1m30s", 数字按秒处理
+	ConfigTypeDuration = "duration"
+)
+
+// ConfigField 配置项的约束
+type ConfigField struct {
+	Key      string          // 配置路径, 不区分大小写, 数组中的元素使用 [] 表示, 如 Db[].Dsn
+	Type     string          // 配置类型, 为空时不校验类型
+	Required bool            // 是否必填
+	Enum     []string        // 可选值, 为空时不校验
+	Check    func(any) error // 自定义校验, 在类型校验通过后执行
+}
+
+// IConfigSchema 模块可选实现的接口, 声明模块依赖的配置, 启动时在加载任何模块之前统一校验
+type IConfigSchema interface {
+	ConfigSchema() []ConfigField
+}
+
+var configSchemas = make(map[string][]ConfigField)
+
+func init() {
+	greasyxCmd.AddCommand(checkConfigCmd)
+}
+
+var checkConfigCmd = &cobra.Command{
+	Use:   "check-config",
+	Short: "校验配置文件",
+	Long:  `按照已加载模块声明的配置约束校验配置文件, 一次性输出所有错误`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := initConfig(); err != nil {
+			console.Echo.Fatalf("❌ 错误: %s\n", err)
+		}
+		if errs := CheckConfig(); len(errs) > 0 {
+			printConfigErrors(errs)
+			os.Exit(ExitCodeError)
+		}
+
+		console.Echo.Infof("✅ 提示: 配置文件 %s 校验通过\n", strings.Join(configFiles, ", "))
+	},
+}
+
+// RegisterConfigSchema 为模块以外的配置声明约束, 如业务自定义的配置
+func RegisterConfigSchema(name string, fields ...ConfigField) {
+	configSchemas[name] = append(configSchemas[name], fields...)
+}
+
+// CheckConfig 校验指定模块及其依赖的配置, 不指定时校验所有已注册的模块, 通过 RegisterConfigSchema 声明的配置总会校验, 返回全部错误
+func CheckConfig(names ...string) []error {
+	schemas := make(map[string][]ConfigField, len(configSchemas))
+	sorted := make([]string, 0, len(configSchemas))
+	for name, fields := range configSchemas {
+		schemas[name] = fields
+		sorted = append(sorted, name)
+	}
+	slices.Sort(sorted)
+
+	modules, err := console.ResolveModules(names...)
+	if err != nil {
+		return []error{err}
+	}
+	for _, module := range modules {
+		if schema, ok := module.(IConfigSchema); ok {
+			schemas[module.Name()] = append(schemas[module.Name()], schema.ConfigSchema()...)
+			if !slices.Contains(sorted, module.Name()) {
+				sorted = append(sorted, module.Name())
+			}
+		}
+	}
+
+	var errs []error
+	for _, name := range sorted {
+		for _, field := range schemas[name] {
+			for _, err := range checkField(configSettings, field) {
+				errs = append(errs, fmt.Errorf("[%s] %w", name, err))
+			}
+		}
+	}
+
+	return errs
+}
+
+func printConfigErrors(errs []error) {
+	console.Echo.Errorf("❌ 错误: 配置文件校验失败, 共 %d 个问题:", len(errs))
+	for _, err := range errs {
+		console.Echo.Errorf("    - %s", err)
+	}
+}
+
+// 配置的值及其完整路径
+type configValue struct {
+	path  string
+	value any
+	found bool
+}
+
+func checkField(settings map[string]any, field ConfigField) []error {
+	var errs []error
+	for _, item := range lookupConfig(settings, "", strings.Split(field.Key, ".")) {
+		if !item.found {
+			if field.Required {
+				errs = append(errs, fmt.Errorf("%s: 必填", item.path))
+			}
+			continue
+		}
+		if err := checkType(item.value, field.Type); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", item.path, err))
+			continue
+		}
+		if len(field.Enum) > 0 && !slices.Contains(field.Enum, fmt.Sprint(item.value)) {
+			errs = append(errs, fmt.Errorf("%s: 可选值为 %s, 当前为 %v", item.path, strings.Join(field.Enum, "/"), item.value))
+			continue
+		}
+		if field.Check != nil {
+			if err := field.Check(item.value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", item.path, err))
+			}
+		}
+	}
+
+	return errs
+}
+
+// 按路径查找配置, 遇到 [] 时展开数组中的每个元素, 数组不存在时不返回任何元素
+func lookupConfig(node map[string]any, prefix string, keys []string) []configValue {
+	key := keys[0]
+	isArray := strings.HasSuffix(key, "[]")
+	key = strings.TrimSuffix(key, "[]")
+	path := key
+	if prefix != "" {
+		path = prefix + "." + key
+	}
+
+	value, found := node[findKey(node, key)]
+	if !found || value == nil {
+		if isArray && len(keys) > 1 {
+			return nil
+		}
+		return []configValue{{path: joinConfigPath(path, keys[1:])}}
+	}
+	if len(keys) == 1 {
+		return []configValue{{path: path, value: value, found: true}}
+	}
+
+	if isArray {
+		list, ok := value.([]any)
+		if !ok {
+			return []configValue{{path: path, value: value, found: true}}
+		}
+		var items []configValue
+		for i, elem := range list {
+			elemPath := fmt.Sprintf("%s[%d]", path, i)
+			child, ok := elem.(map[string]any)
+			if !ok {
+				items = append(items, configValue{path: elemPath + "." + keys[1], value: elem, found: true})
+				continue
+			}
+			items = append(items, lookupConfig(child, elemPath, keys[1:])...)
+		}
+		return items
+	}
+
+	child, ok := value.(map[string]any)
+	if !ok {
+		return []configValue{{path: joinConfigPath(path, keys[1:])}}
+	}
+
+	return lookupConfig(child, path, keys[1:])
+}
+
+func joinConfigPath(path string, keys []string) string {
+	if len(keys) == 0 {
+		return path
+	}
+
+	return path + "." + strings.Join(keys, ".")
+}
+
+// 环境变量新增的配置都是字符串, 所以数字和布尔类型也接受可以转换的字符串
+func checkType(value any, typ string) error {
+	ok := true
+	switch typ {
+	case ConfigTypeString:
+		_, ok = value.(string)
+	case ConfigTypeInt:
+		switch v := value.(type) {
+		case int, int64:
+		case float64:
+			ok = v == float64(int64(v))
+		case string:
+			_, err := strconv.ParseInt(v, 10, 64)
+			ok = err == nil
+		default:
+			ok = false
+		}
+	case ConfigTypeFloat:
+		switch v := value.(type) {
+		case int, int64, float64:
+		case string:
+			_, err := strconv.ParseFloat(v, 64)
+			ok = err == nil
+		default:
+			ok = false
+		}
+	case ConfigTypeBool:
+		switch v := value.(type) {
+		case bool:
+		case string:
+			_, err := strconv.ParseBool(v)
+			ok = err == nil
+		default:
+			ok = false
+		}
+	case ConfigTypeArray:
+		_, ok = value.([]any)
+	case ConfigTypeMap:
+		_, ok = value.(map[string]any)
+	case ConfigTypeDuration:
+		if _, err := ginahelper.ToDuration(value); err != nil {
+			return err
+		}
+	}
+	if !ok {
+		return fmt.Errorf("类型应为 %s, 当前为 %T", typ, value)
+	}
+
+	return nil
+}
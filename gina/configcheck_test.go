@@ -0,0 +1,86 @@
+package gina
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestCheckField(t *testing.T) {
+	settings := map[string]any{
+		"app": map[string]any{
+			"addr":            ":8080",
+			"env":             "prod",
+			"watchconfig":     "true",
+			"shutdowntimeout": "30s",
+			"server":          "x",
+		},
+		"db": []any{
+			map[string]any{"dsn": "a", "maxidle": float64(10)},
+			map[string]any{"maxidle": 1.5},
+		},
+		"redis": map[string]any{"db": "0", "ratio": "0.5", "timeout": "abc"},
+	}
+
+	tests := []struct {
+		name  string
+		field ConfigField
+		want  []string
+	}{
+		{name: "必填且存在", field: ConfigField{Key: "App.Addr", Type: ConfigTypeString, Required: true}},
+		{name: "必填但不存在", field: ConfigField{Key: "App.Name", Required: true}, want: []string{"App.Name: 必填"}},
+		{name: "可选且不存在", field: ConfigField{Key: "App.Name", Type: ConfigTypeString}},
+		{name: "父级不是对象", field: ConfigField{Key: "App.Server.Addr", Required: true}, want: []string{"App.Server.Addr: 必填"}},
+		{
+			name:  "类型不匹配",
+			field: ConfigField{Key: "App.Addr", Type: ConfigTypeInt},
+			want:  []string{"App.Addr: 类型应为 int, 当前为 string"},
+		},
+		{name: "可以转换的字符串", field: ConfigField{Key: "App.WatchConfig", Type: ConfigTypeBool}},
+		{name: "字符串形式的整数", field: ConfigField{Key: "Redis.Db", Type: ConfigTypeInt}},
+		{name: "字符串形式的小数", field: ConfigField{Key: "Redis.Ratio", Type: ConfigTypeFloat}},
+		{name: "时间", field: ConfigField{Key: "App.ShutdownTimeout", Type: ConfigTypeDuration}},
+		{
+			name:  "不合法的时间",
+			field: ConfigField{Key: "Redis.Timeout", Type: ConfigTypeDuration},
+			want:  []string{`Redis.Timeout: "abc" 不是合法的时间, 如 30s、1m30s`},
+		},
+		{
+			name:  "可选值",
+			field: ConfigField{Key: "App.Env", Enum: []string{"dev", "test"}},
+			want:  []string{"App.Env: 可选值为 dev/test, 当前为 prod"},
+		},
+		{
+			name:  "自定义校验",
+			field: ConfigField{Key: "App.Env", Check: func(any) error { return errors.New("不支持") }},
+			want:  []string{"App.Env: 不支持"},
+		},
+		{
+			name:  "类型不匹配时不执行自定义校验",
+			field: ConfigField{Key: "App.Env", Type: ConfigTypeInt, Check: func(any) error { return errors.New("不支持") }},
+			want:  []string{"App.Env: 类型应为 int, 当前为 string"},
+		},
+		{
+			name:  "展开数组中的每个元素",
+			field: ConfigField{Key: "Db[].Dsn", Type: ConfigTypeString, Required: true},
+			want:  []string{"Db[1].Dsn: 必填"},
+		},
+		{
+			name:  "数组中的整数",
+			field: ConfigField{Key: "Db[].MaxIdle", Type: ConfigTypeInt},
+			want:  []string{"Db[1].MaxIdle: 类型应为 int, 当前为 float64"},
+		},
+		{name: "数组不存在", field: ConfigField{Key: "Mongo[].Uri", Required: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, err := range checkField(settings, tt.field) {
+				got = append(got, err.Error())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("错误为 %q, 应该为 %q", got, tt.want)
+			}
+		})
+	}
+}
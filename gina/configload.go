@@ -0,0 +1,228 @@
+package gina
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/soryetong/greasyx/console"
+	"github.com/spf13/viper"
+)
+
+const (
+	// EnvPrefix 环境变量覆盖配置时使用的前缀, 如 GREASYX_DB_0_DSN 对应 Db[0].Dsn
+	EnvPrefix = "GREASYX_"
+	// fileRefPrefix 以该前缀开头的配置值会被替换为对应文件的内容, 如 Kubernetes 挂载的 Secret
+	fileRefPrefix = "file://"
+)
+
+// 实际参与合并的配置文件, 配置热更新时会监听这些文件
+var configFiles []string
+
+// 按顺序合并基础配置、环境配置 config.<env>.json、GREASYX_ 前缀的环境变量, 最后替换 file:// 引用
+func loadConfig(path string) (map[string]any, []string, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, nil, err
+	}
+	files := []string{path}
+
+	env := os.Getenv(EnvPrefix + "APP_ENV")
+	if env == "" {
+		env = v.GetString("App.Env")
+	}
+	if env != "" {
+		ext := filepath.Ext(path)
+		overlay := strings.TrimSuffix(path, ext) + "." + env + ext
+		switch _, err := os.Stat(overlay); {
+		case err == nil:
+			v.SetConfigFile(overlay)
+			if err = v.MergeInConfig(); err != nil {
+				return nil, nil, fmt.Errorf("合并环境配置 %s 失败: %w", overlay, err)
+			}
+			files = append(files, overlay)
+		case errors.Is(err, fs.ErrNotExist):
+			console.Echo.Warnf("⚠️ 警告: 当前环境为 %s, 但环境配置 %s 不存在, 只使用 %s\n", env, overlay, path)
+		default:
+			return nil, nil, fmt.Errorf("读取环境配置 %s 失败: %w", overlay, err)
+		}
+	}
+
+	settings := v.AllSettings()
+	if err := applyEnvOverrides(settings, os.Environ()); err != nil {
+		return nil, nil, err
+	}
+	if err := resolveFileRefs(settings); err != nil {
+		return nil, nil, err
+	}
+
+	return settings, files, nil
+}
+
+// 使用环境变量覆盖配置, 变量名按 _ 分隔为配置路径, 数字表示数组下标, 不区分大小写
+// 按配置路径排序后依次覆盖, 下标按数字比较, 保证 GREASYX_DB_2_DSN 在 GREASYX_DB_10_DSN 之前, 数组可以逐个追加
+func applyEnvOverrides(settings map[string]any, environ []string) error {
+	type override struct {
+		key   string
+		path  []string
+		value string
+	}
+	var overrides []override
+	for _, kv := range environ {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(key, EnvPrefix) {
+			continue
+		}
+		overrides = append(overrides, override{key: key, path: strings.Split(strings.TrimPrefix(key, EnvPrefix), "_"), value: value})
+	}
+	slices.SortStableFunc(overrides, func(a, b override) int {
+		return comparePath(a.path, b.path)
+	})
+
+	for _, item := range overrides {
+		if err := setPath(settings, item.path, item.value); err != nil {
+			return fmt.Errorf("环境变量 %s 无法覆盖配置: %w", item.key, err)
+		}
+	}
+
+	return nil
+}
+
+// 逐段比较配置路径, 两段都是数字时按数字比较, 其他按字符串比较
+func comparePath(a, b []string) int {
+	for i := range min(len(a), len(b)) {
+		x, errX := strconv.Atoi(a[i])
+		y, errY := strconv.Atoi(b[i])
+		if errX == nil && errY == nil {
+			if c := cmp.Compare(x, y); c != 0 {
+				return c
+			}
+			continue
+		}
+		if c := strings.Compare(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+
+	return cmp.Compare(len(a), len(b))
+}
+
+func setPath(node map[string]any, path []string, value string) error {
+	key := findKey(node, path[0])
+	if len(path) == 1 {
+		node[key] = convertLike(node[key], value)
+		return nil
+	}
+
+	switch child := node[key].(type) {
+	case map[string]any:
+		return setPath(child, path[1:], value)
+	case []any:
+		index, err := strconv.Atoi(path[1])
+		if err != nil || index < 0 || index > len(child) {
+			return fmt.Errorf("%s 的下标 %s 不合法", path[0], path[1])
+		}
+		if index == len(child) {
+			child = append(child, map[string]any{})
+			node[key] = child
+		}
+		if len(path) == 2 {
+			child[index] = convertLike(child[index], value)
+			return nil
+		}
+		item, ok := child[index].(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s[%d] 不是对象", path[0], index)
+		}
+		return setPath(item, path[2:], value)
+	case nil:
+		// 下一段为数字时创建数组, 如没有 Db 配置时的 GREASYX_DB_0_DSN
+		if _, err := strconv.Atoi(path[1]); err == nil {
+			node[key] = []any{}
+		} else {
+			node[key] = make(map[string]any)
+		}
+		return setPath(node, path, value)
+	default:
+		return fmt.Errorf("%s 不是对象", path[0])
+	}
+}
+
+// 查找不区分大小写的已有键, 不存在时使用小写的键名
+func findKey(node map[string]any, name string) string {
+	for key := range node {
+		if strings.EqualFold(key, name) {
+			return key
+		}
+	}
+
+	return strings.ToLower(name)
+}
+
+// 按原有值的类型转换环境变量的值, 新增的键按字符串处理
+func convertLike(old any, value string) any {
+	switch old.(type) {
+	case bool:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case int, int64:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case float64:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+
+	return value
+}
+
+// 将 file:// 开头的字符串替换为文件内容, 去掉末尾的换行
+func resolveFileRefs(node any) error {
+	switch val := node.(type) {
+	case map[string]any:
+		for key, item := range val {
+			resolved, err := resolveFileRef(item)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			val[key] = resolved
+		}
+	case []any:
+		for i, item := range val {
+			resolved, err := resolveFileRef(item)
+			if err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+			val[i] = resolved
+		}
+	}
+
+	return nil
+}
+
+func resolveFileRef(item any) (any, error) {
+	str, ok := item.(string)
+	if !ok {
+		return item, resolveFileRefs(item)
+	}
+	if !strings.HasPrefix(str, fileRefPrefix) {
+		return item, nil
+	}
+
+	data, err := os.ReadFile(strings.TrimPrefix(str, fileRefPrefix))
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
@@ -0,0 +1,249 @@
+package gina
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/soryetong/greasyx/libs/ginagrace"
+)
+
+// 重启时 ginagrace.Restart 以相同的参数启动测试程序自身, 新进程加载该环境变量指定的配置文件
+const envRestartConfig = "GINA_TEST_RESTART_CONFIG"
+
+func TestMain(m *testing.M) {
+	if path := os.Getenv(envRestartConfig); path != "" && ginagrace.IsChild() {
+		if err := checkRestartConfig(path); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		ginagrace.Ready()
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// 新进程加载的配置中不应该出现 ginagrace 传递监听时使用的环境变量
+func checkRestartConfig(path string) error {
+	settings, _, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	for _, key := range []string{"listen", "ready"} {
+		if value, ok := settings[key]; ok {
+			return fmt.Errorf("配置中出现了 %s: %v", key, value)
+		}
+	}
+
+	return nil
+}
+
+func TestSetPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]any
+		path     []string
+		value    string
+		want     map[string]any
+		wantErr  bool
+	}{
+		{
+			name:     "覆盖已有的键并保留类型",
+			settings: map[string]any{"app": map[string]any{"addr": ":8080", "watchconfig": false, "timeout": int64(1)}},
+			path:     []string{"APP", "WATCHCONFIG"},
+			value:    "true",
+			want:     map[string]any{"app": map[string]any{"addr": ":8080", "watchconfig": true, "timeout": int64(1)}},
+		},
+		{
+			name:     "新增的键为字符串",
+			settings: map[string]any{},
+			path:     []string{"APP", "ADDR"},
+			value:    ":9090",
+			want:     map[string]any{"app": map[string]any{"addr": ":9090"}},
+		},
+		{
+			name:     "覆盖数组中的元素",
+			settings: map[string]any{"db": []any{map[string]any{"dsn": "a", "driver": "mysql"}}},
+			path:     []string{"DB", "0", "DSN"},
+			value:    "b",
+			want:     map[string]any{"db": []any{map[string]any{"dsn": "b", "driver": "mysql"}}},
+		},
+		{
+			name:     "在数组末尾追加元素",
+			settings: map[string]any{"db": []any{map[string]any{"dsn": "a"}}},
+			path:     []string{"DB", "1", "DSN"},
+			value:    "b",
+			want:     map[string]any{"db": []any{map[string]any{"dsn": "a"}, map[string]any{"dsn": "b"}}},
+		},
+		{
+			name:     "不存在的数组按数字下标创建",
+			settings: map[string]any{},
+			path:     []string{"DB", "0", "DSN"},
+			value:    "a",
+			want:     map[string]any{"db": []any{map[string]any{"dsn": "a"}}},
+		},
+		{
+			name:     "直接设置数组的元素",
+			settings: map[string]any{},
+			path:     []string{"CORS", "ALLOWORIGINS", "0"},
+			value:    "*",
+			want:     map[string]any{"cors": map[string]any{"alloworigins": []any{"*"}}},
+		},
+		{
+			name:     "下标不连续",
+			settings: map[string]any{},
+			path:     []string{"DB", "1", "DSN"},
+			value:    "a",
+			wantErr:  true,
+		},
+		{
+			name:     "下标不是数字",
+			settings: map[string]any{"db": []any{}},
+			path:     []string{"DB", "X", "DSN"},
+			value:    "a",
+			wantErr:  true,
+		},
+		{
+			name:     "中间的配置不是对象",
+			settings: map[string]any{"app": "x"},
+			path:     []string{"APP", "ADDR"},
+			value:    "a",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := setPath(tt.settings, tt.path, tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("应该返回错误, 实际为 %v", tt.settings)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tt.settings, tt.want) {
+				t.Fatalf("覆盖后为 %v, 应该为 %v", tt.settings, tt.want)
+			}
+		})
+	}
+}
+
+// 环境变量的顺序不固定, 数组的元素需要按下标依次追加
+func TestApplyEnvOverridesOrder(t *testing.T) {
+	settings := map[string]any{}
+	environ := []string{"GREASYX_DB_1_DSN=b", "PATH=/bin", "GREASYX_DB_0_DSN=a", "GREASYX_DB_0_DRIVER=mysql"}
+	if err := applyEnvOverrides(settings, environ); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{"db": []any{map[string]any{"dsn": "a", "driver": "mysql"}, map[string]any{"dsn": "b"}}}
+	if !reflect.DeepEqual(settings, want) {
+		t.Fatalf("覆盖后为 %v, 应该为 %v", settings, want)
+	}
+}
+
+// 下标按数字排序, GREASYX_DB_10_DSN 在 GREASYX_DB_9_DSN 之后追加
+func TestApplyEnvOverridesIndex(t *testing.T) {
+	settings := map[string]any{}
+	environ := make([]string, 0, 11)
+	want := make([]any, 0, 11)
+	for i := 10; i >= 0; i-- {
+		environ = append(environ, fmt.Sprintf("GREASYX_DB_%d_DSN=dsn%d", i, i))
+	}
+	for i := range 11 {
+		want = append(want, map[string]any{"dsn": fmt.Sprintf("dsn%d", i)})
+	}
+	if err := applyEnvOverrides(settings, environ); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(settings, map[string]any{"db": want}) {
+		t.Fatalf("覆盖后为 %v", settings)
+	}
+}
+
+func TestLoadConfigOverlay(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       string
+		overlay   string
+		wantAddr  string
+		wantFiles int
+	}{
+		{name: "合并环境配置", env: "prod", overlay: `{"App": {"Addr": ":9090"}}`, wantAddr: ":9090", wantFiles: 2},
+		{name: "环境配置不存在时只使用基础配置", env: "staging", wantAddr: ":8080", wantFiles: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.json")
+			writeFile(t, path, `{"App": {"Addr": ":8080", "Name": "app"}}`)
+			if tt.overlay != "" {
+				writeFile(t, filepath.Join(dir, "config."+tt.env+".json"), tt.overlay)
+			}
+			t.Setenv(EnvPrefix+"APP_ENV", tt.env)
+
+			settings, files, err := loadConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			app := settings["app"].(map[string]any)
+			if app["addr"] != tt.wantAddr || app["name"] != "app" {
+				t.Fatalf("合并后为 %v, Addr 应该为 %s", app, tt.wantAddr)
+			}
+			if len(files) != tt.wantFiles {
+				t.Fatalf("参与合并的配置文件为 %v, 应该有 %d 个", files, tt.wantFiles)
+			}
+		})
+	}
+}
+
+// 重启后新进程的配置与旧进程一致
+func TestRestartConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeFile(t, path, `{"App": {"Addr": ":8080"}}`)
+	t.Setenv(envRestartConfig, path)
+
+	ln, err := ginagrace.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err = ginagrace.Restart(ctx); err != nil {
+		t.Fatalf("新进程的配置不正确: %s", err)
+	}
+}
+
+func TestResolveFileRefs(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "dsn")
+	writeFile(t, secret, "root:123456@tcp(127.0.0.1:3306)/app\n")
+
+	settings := map[string]any{"db": []any{map[string]any{"dsn": "file://" + secret}}}
+	if err := resolveFileRefs(settings); err != nil {
+		t.Fatal(err)
+	}
+	if dsn := settings["db"].([]any)[0].(map[string]any)["dsn"]; dsn != "root:123456@tcp(127.0.0.1:3306)/app" {
+		t.Fatalf("file:// 应该替换为去掉换行的文件内容, 实际为 %q", dsn)
+	}
+
+	missing := map[string]any{"dsn": "file://" + secret + ".missing"}
+	if err := resolveFileRefs(missing); err == nil {
+		t.Fatal("文件不存在时应该返回错误")
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
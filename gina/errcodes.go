@@ -0,0 +1,103 @@
+package gina
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/soryetong/greasyx/console"
+	"github.com/soryetong/greasyx/libs/ginaerror"
+	"github.com/spf13/cobra"
+)
+
+var (
+	errcodesFormat string
+	errcodesOutput string
+)
+
+func init() {
+	errcodesCmd.Flags().StringVarP(&errcodesFormat, "format", "f", "json", "导出格式, 可选 json、markdown")
+	errcodesCmd.Flags().StringVarP(&errcodesOutput, "output", "o", "", "导出的文件, 为空时输出到终端")
+	greasyxCmd.AddCommand(errcodesCmd)
+}
+
+var errcodesCmd = &cobra.Command{
+	Use:   "errcodes",
+	Short: "导出错误码表",
+	Long:  `导出内置的、通过 ginaerror.Register 注册的和 App.LocaleDir 中的错误码, 包括 HTTP 状态码和各语言的提示, 供前端使用`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// 配置文件只用于读取 App.LocaleDir, 读取失败时只导出代码中的错误码
+		if err := initConfig(); err != nil {
+			console.Echo.Warnf("⚠️ 警告: %s, 只导出代码中的错误码\n", err)
+		} else if err = initLocale(); err != nil {
+			console.Echo.Fatalf("❌ 错误: %s\n", err)
+		}
+
+		var out io.Writer = os.Stdout
+		if errcodesOutput != "" {
+			file, err := os.Create(errcodesOutput)
+			if err != nil {
+				console.Echo.Fatalf("❌ 错误: 创建文件 %s 失败: %s\n", errcodesOutput, err)
+			}
+			defer file.Close()
+			out = file
+		}
+
+		if err := ExportErrorCodes(out, errcodesFormat); err != nil {
+			console.Echo.Fatalf("❌ 错误: %s\n", err)
+		}
+		if errcodesOutput != "" {
+			console.Echo.Infof("✅ 提示: 错误码表已导出到 %s\n", errcodesOutput)
+		}
+	},
+}
+
+// ExportErrorCodes 以 json 或 markdown 格式导出错误码表, 按错误码排序
+func ExportErrorCodes(w io.Writer, format string) error {
+	codes := ginaerror.Codes()
+	switch strings.ToLower(format) {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(codes)
+	case "markdown", "md":
+		return writeErrorCodesMarkdown(w, codes)
+	default:
+		return fmt.Errorf("不支持的导出格式 %s, 可选 json、markdown", format)
+	}
+}
+
+// 每种语言一列, 默认语言在最前面, 其他语言按名称排序
+func writeErrorCodesMarkdown(w io.Writer, codes []ginaerror.CodeInfo) error {
+	locales := ginaerror.Locales()
+	slices.SortFunc(locales, func(a, b string) int {
+		switch ginaerror.DefaultLocale() {
+		case a:
+			return -1
+		case b:
+			return 1
+		}
+		return strings.Compare(a, b)
+	})
+
+	var builder strings.Builder
+	builder.WriteString("| 错误码 | 名称 | HTTP 状态码 | 映射的 HTTP 状态码 |")
+	for _, locale := range locales {
+		builder.WriteString(" " + locale + " |")
+	}
+	builder.WriteString("\n|---|---|---|---|" + strings.Repeat("---|", len(locales)) + "\n")
+	for _, info := range codes {
+		builder.WriteString(fmt.Sprintf("| %d | %s | %d | %d |", info.Code, info.Name, info.Status, info.Mapped))
+		for _, locale := range locales {
+			builder.WriteString(" " + strings.ReplaceAll(info.Messages[locale], "|", "\\|") + " |")
+		}
+		builder.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
@@ -64,6 +64,20 @@ func GetSqlx(driver string) *sqlx.DB {
 	return val.(*sqlx.DB)
 }
 
+// RangeGorm 遍历所有已注册的 gorm 实例, fn 返回 false 时停止
+func RangeGorm(fn func(driver string, db *gorm.DB) bool) {
+	odbMap.Range(func(key, value any) bool {
+		return fn(key.(string), value.(*gorm.DB))
+	})
+}
+
+// RangeSqlx 遍历所有已注册的 sqlx 实例, fn 返回 false 时停止
+func RangeSqlx(fn func(driver string, db *sqlx.DB) bool) {
+	xdbMap.Range(func(key, value any) bool {
+		return fn(key.(string), value.(*sqlx.DB))
+	})
+}
+
 // === （GORM） ===
 func GMySQL() *gorm.DB     { return GetGorm(DbTypeMysql) }
 func GPostgres() *gorm.DB  { return GetGorm(DbTypePostgresql) }
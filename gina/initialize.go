@@ -1,52 +1,196 @@
 package gina
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/soryetong/greasyx/console"
+	"github.com/soryetong/greasyx/libs/ginaerror"
 	"github.com/soryetong/greasyx/modules/cachemodule"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
-var configFile string
+var (
+	configFile   string
+	configMap    map[string]any // 通过 SetupWithConfig 传入的配置, 不为空时不再读取配置文件
+	setupModules []string       // 以库的方式使用时只校验这些模块的配置
+)
 
 func init() {
-	console.RootCmd.Flags().StringVarP(&configFile, "config", "c", "", "config file")
+	console.RootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "config file")
 	console.RootCmd.CompletionOptions.DisableDefaultCmd = true
-	console.Append(greasyxCmd)
+	console.AppendModule(&ginaModule{}, greasyxCmd)
 }
 
+// ModuleName Greasyx框架模块的名称, 其他模块都需要依赖它
+const ModuleName = "Gina"
+
 var greasyxCmd = &cobra.Command{
-	Use:   "Gina", // 命令名称, 不要修改
+	Use:   ModuleName, // 命令名称, 不要修改
 	Short: "Greasyx框架初始化",
 	Long:  `Greasyx框架初始化`,
-	Run: func(cmd *cobra.Command, args []string) {
-		// 初始化配置文件
-		initConfig()
-		// 初始化日志
-		initILog()
-		// 初始化缓存模块
-		Cache = cachemodule.New(10000, 64, 0)
-	},
-}
-
-func initConfig() {
-	// 加载配置
-	if configFile == "" {
-		configFile = "./config.json"
+}
+
+type ginaModule struct{}
+
+func (self *ginaModule) Name() string {
+	return ModuleName
+}
+
+func (self *ginaModule) DependsOn() []string {
+	return nil
+}
+
+func (self *ginaModule) Init() error {
+	// 初始化配置文件, 并在加载其他模块之前校验所有模块的配置
+	if err := initConfig(); err != nil {
+		return err
 	}
-	viper.SetConfigFile(configFile)
-	if err := viper.ReadInConfig(); err != nil {
-		console.Echo.Fatalf("❌ 错误: 读取配置文件错误: %s", err)
+	if errs := CheckConfig(setupModules...); len(errs) > 0 {
+		printConfigErrors(errs)
+		return fmt.Errorf("配置文件校验失败, 共 %d 个问题", len(errs))
+	}
+	// 初始化日志
+	if err := initILog(); err != nil {
+		return err
+	}
+	// 初始化多语言
+	if err := initLocale(); err != nil {
+		return err
+	}
+	// 替换 gin 的校验器并注册参数校验错误的翻译
+	ginaerror.Setup()
+	// 初始化响应的格式
+	initResponse()
+	// 配置热更新
+	if viper.GetBool("App.WatchConfig") {
+		watchConfig()
+	}
+	// 初始化缓存模块
+	Cache = cachemodule.New(10000, 64, 0)
+
+	return nil
+}
+
+func (self *ginaModule) ConfigSchema() []ConfigField {
+	return []ConfigField{
+		{Key: "App.Addr", Type: ConfigTypeString},
+		{Key: "App.Env", Type: ConfigTypeString},
+		{Key: "App.RouterPrefix", Type: ConfigTypeString},
+		{Key: "App.ShutdownTimeout", Type: ConfigTypeDuration},
+		{Key: "App.WatchConfig", Type: ConfigTypeBool},
+		{Key: "App.Locale", Type: ConfigTypeString},
+		{Key: "App.LocaleDir", Type: ConfigTypeString},
+		{Key: "App.Response.Mode", Type: ConfigTypeString, Enum: []string{ResponseModeEnvelope, ResponseModeProblem}},
+		{Key: "App.Response.HTTPStatus", Type: ConfigTypeBool},
+		{Key: "App.Response.OmitTiming", Type: ConfigTypeBool},
+		{Key: "App.Response.Fields", Type: ConfigTypeMap},
+		{Key: "App.Response.ProblemType", Type: ConfigTypeString},
+		{Key: "App.Server.ReadHeaderTimeout", Type: ConfigTypeDuration},
+		{Key: "App.Server.ReadTimeout", Type: ConfigTypeDuration},
+		{Key: "App.Server.WriteTimeout", Type: ConfigTypeDuration},
+		{Key: "App.Server.IdleTimeout", Type: ConfigTypeDuration},
+		{Key: "App.Server.MaxHeaderBytes", Type: ConfigTypeInt},
+		{Key: "Log.Path", Type: ConfigTypeString},
+		{Key: "Log.Mode", Type: ConfigTypeString, Enum: []string{"file", "console", "both", "close"}},
+		{Key: "Log.Level", Type: ConfigTypeString, Enum: []string{"debug", "info", "warn", "error"}},
+		{Key: "Log.Recover", Type: ConfigTypeBool},
+		{Key: "Log.MaxSize", Type: ConfigTypeInt},
+		{Key: "Log.MaxBackups", Type: ConfigTypeInt},
+		{Key: "Log.MaxAge", Type: ConfigTypeInt},
+		{Key: "Log.Compress", Type: ConfigTypeBool},
+		{Key: "Log.Logrotate", Type: ConfigTypeBool},
+		{Key: "Cors.AllowOrigins", Type: ConfigTypeArray},
+	}
+}
+
+// 加载 App.LocaleDir 中自定义的错误码提示, 并设置默认语言, 需要在加载之后设置, 否则自定义的语言不会生效
+func initLocale() error {
+	if dir := viper.GetString("App.LocaleDir"); dir != "" {
+		if err := ginaerror.LoadCatalogDir(dir); err != nil {
+			return err
+		}
+	}
+	if locale := viper.GetString("App.Locale"); locale != "" {
+		ginaerror.SetDefaultLocale(locale)
+	}
+
+	return nil
+}
+
+func (self *ginaModule) Close() error {
+	stopWatchConfig()
+	if rotationScheduler != nil {
+		rotationScheduler.Stop()
+	}
+	if Cache != nil {
+		Cache.Close()
+	}
+	_ = Log.Sync()
+
+	return nil
+}
+
+// Setup 以库的方式使用 Greasyx 时的入口, 加载配置文件并初始化 Gina 模块, 失败时返回错误而不是退出程序
+// 其他模块可以继续通过 console.InitModules 加载, 程序退出前调用 console.CloseModules 释放资源
+func Setup(path string) error {
+	configFile = path
+	configMap = nil
+	setupModules = []string{ModuleName}
+	return console.InitModules(ModuleName)
+}
+
+// SetupWithConfig 同 Setup, 但配置直接从 settings 中读取, 不读取配置文件和环境变量, 一般用于测试
+func SetupWithConfig(settings map[string]any) error {
+	configMap = settings
+	setupModules = []string{ModuleName}
+	return console.InitModules(ModuleName)
+}
+
+func initConfig() error {
+	settings, files, err := readConfig()
+	if err != nil {
+		return fmt.Errorf("读取配置文件错误: %w", err)
 	}
+	if err = replaceConfig(settings); err != nil {
+		return fmt.Errorf("读取配置文件错误: %w", err)
+	}
+	configSettings = settings
+	configFiles = files
 
 	// 设置默认值
 	viper.SetDefault("App.Env", "test")
+	viper.SetDefault("App.ShutdownTimeout", DefaultShutdownTimeout.String())
 	routerPrefix := viper.GetString("App.RouterPrefix")
 	if routerPrefix == "" {
 		viper.SetDefault("App.RouterPrefix", "/api/v1")
 	} else {
 		viper.Set("App.RouterPrefix", "/"+strings.Trim(routerPrefix, "/"))
 	}
+
+	return nil
+}
+
+// 优先使用 SetupWithConfig 传入的配置, 键名和配置文件一样统一转为小写
+func readConfig() (map[string]any, []string, error) {
+	if configMap != nil {
+		v := viper.New()
+		if err := v.MergeConfigMap(configMap); err != nil {
+			return nil, nil, err
+		}
+		settings := v.AllSettings()
+		return settings, nil, resolveFileRefs(settings)
+	}
+
+	if configFile == "" {
+		configFile = "./config.json"
+	}
+	settings, files, err := loadConfig(configFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	viper.SetConfigFile(configFile)
+
+	return settings, files, nil
 }
@@ -9,7 +9,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/soryetong/greasyx/console"
 	"github.com/soryetong/greasyx/ginahelper"
+	"github.com/soryetong/greasyx/libs/ginatrace"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -18,11 +20,21 @@ import (
 
 var rotationScheduler *RotationScheduler
 
+// 日志级别, 支持通过配置热更新
+var (
+	logLevel     = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	logWatchOnce sync.Once
+)
+
+type logConfig struct {
+	Level string
+}
+
 type ILog struct {
 	*zap.Logger
 }
 
-func initILog() {
+func initILog() error {
 	viper.SetDefault("Log.Path", "./logs")
 	viper.SetDefault("Log.Mode", "both")
 	viper.SetDefault("Log.Recover", false)
@@ -31,6 +43,10 @@ func initILog() {
 	viper.SetDefault("Log.MaxAge", 7)
 	viper.SetDefault("Log.Compress", true)
 	viper.SetDefault("Log.Logrotate", true)
+	viper.SetDefault("Log.Level", "debug")
+	if err := logLevel.UnmarshalText([]byte(viper.GetString("Log.Level"))); err != nil {
+		return fmt.Errorf("Log.Level 配置错误: %w", err)
+	}
 	newILog()
 
 	// 日志轮转
@@ -39,6 +55,27 @@ func initILog() {
 			newILog()
 		})
 	}
+
+	// 重复初始化时, 如测试中多次调用 SetupWithConfig, 只订阅一次
+	logWatchOnce.Do(func() {
+		OnConfigChange("Log", func(old, new logConfig) {
+			_ = logLevel.UnmarshalText([]byte(levelOrDefault(new.Level)))
+			console.Echo.Infof("✅ 提示: 日志级别已更新为 %s\n", logLevel.String())
+		}, func(new logConfig) error {
+			var level zapcore.Level
+			return level.UnmarshalText([]byte(levelOrDefault(new.Level)))
+		})
+	})
+
+	return nil
+}
+
+func levelOrDefault(level string) string {
+	if level == "" {
+		return "debug"
+	}
+
+	return level
 }
 
 func newILog() {
@@ -58,6 +95,7 @@ func (l *ILog) With(fields ...zap.Field) *ILog {
 	}
 }
 
+// WithCtx 添加 ctx 中的 trace_id、span_id 和 source, 用于把日志和链路关联起来
 func (l *ILog) WithCtx(ctx context.Context) *ILog {
 	var traceIdStr, sourceStr string
 	traceId := ctx.Value("trace_id")
@@ -70,11 +108,18 @@ func (l *ILog) WithCtx(ctx context.Context) *ILog {
 		sourceStr, _ = source.(string)
 	}
 
+	span := ginatrace.SpanFromContext(ctx)
+	if traceIdStr == "" {
+		traceIdStr = span.TraceID()
+	}
 	if traceIdStr != "" {
-		l.With(zap.String("trace_id", traceIdStr))
+		l = l.With(zap.String("trace_id", traceIdStr))
+	}
+	if span != nil {
+		l = l.With(zap.String("span_id", span.SpanContext().SpanID.String()))
 	}
 	if sourceStr != "" {
-		l.With(zap.String("source", sourceStr))
+		l = l.With(zap.String("source", sourceStr))
 	}
 
 	return l
@@ -95,19 +140,19 @@ func getCore() zapcore.Core {
 	errorWrite := getLogWriter(path, mode, doRecover, zapcore.ErrorLevel)
 	fatalWrite := getLogWriter(path, mode, doRecover, zapcore.FatalLevel)
 	debugLevel := zap.LevelEnablerFunc(func(level zapcore.Level) bool {
-		return level == zapcore.DebugLevel
+		return level == zapcore.DebugLevel && logLevel.Enabled(level)
 	})
 	infoLevel := zap.LevelEnablerFunc(func(level zapcore.Level) bool {
-		return level == zapcore.InfoLevel
+		return level == zapcore.InfoLevel && logLevel.Enabled(level)
 	})
 	warnLevel := zap.LevelEnablerFunc(func(level zapcore.Level) bool {
-		return level == zapcore.WarnLevel
+		return level == zapcore.WarnLevel && logLevel.Enabled(level)
 	})
 	errorLevel := zap.LevelEnablerFunc(func(level zapcore.Level) bool {
-		return level == zapcore.ErrorLevel
+		return level == zapcore.ErrorLevel && logLevel.Enabled(level)
 	})
 	fatalLevel := zap.LevelEnablerFunc(func(level zapcore.Level) bool {
-		return level == zapcore.FatalLevel
+		return level == zapcore.FatalLevel && logLevel.Enabled(level)
 	})
 	return zapcore.NewTee(
 		zapcore.NewCore(encoder, zapcore.AddSync(debugWrite), debugLevel),
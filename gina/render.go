@@ -0,0 +1,178 @@
+package gina
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/soryetong/greasyx/libs/ginaerror"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// 响应的格式
+const (
+	ResponseModeEnvelope = "envelope" // {code,msg,data,nowTime,useTime}, 字段名可以配置
+	ResponseModeProblem  = "problem"  // 错误按 RFC 7807 返回 application/problem+json, 成功与 envelope 一致
+)
+
+// Renderer 把响应写入 ctx, Success、Fail、Error 和 Result 都通过它返回, status 为最终的 HTTP 状态码
+type Renderer interface {
+	Render(ctx *gin.Context, status int, resp *Response)
+}
+
+// EnvelopeFields 响应中各字段的名称, 为空时使用默认的名称
+type EnvelopeFields struct {
+	Code    string
+	Msg     string
+	Data    string
+	NowTime string
+	UseTime string
+}
+
+// EnvelopeRenderer 以 JSON 对象返回, 默认与 Response 的字段一致
+type EnvelopeRenderer struct {
+	Fields     EnvelopeFields
+	OmitTiming bool // 不返回 nowTime 和 useTime
+}
+
+func (self *EnvelopeRenderer) Render(ctx *gin.Context, status int, resp *Response) {
+	if self.Fields == (EnvelopeFields{}) && !self.OmitTiming {
+		ctx.JSON(status, resp)
+		return
+	}
+
+	body := gin.H{
+		envelopeKey(self.Fields.Code, "code"): resp.Code,
+		envelopeKey(self.Fields.Msg, "msg"):   resp.Msg,
+		envelopeKey(self.Fields.Data, "data"): resp.Data,
+	}
+	if !self.OmitTiming {
+		body[envelopeKey(self.Fields.NowTime, "nowTime")] = resp.NowTime
+		body[envelopeKey(self.Fields.UseTime, "useTime")] = resp.UseTime
+	}
+	ctx.JSON(status, body)
+}
+
+func envelopeKey(name, defaultName string) string {
+	if name == "" {
+		return defaultName
+	}
+
+	return name
+}
+
+// ProblemRenderer 错误按 RFC 7807 返回, 业务错误码放在扩展字段 code 中, Data 不为空时放在 data 中; 成功的响应交给 Success
+type ProblemRenderer struct {
+	Success  Renderer // 为 nil 时使用默认的 EnvelopeRenderer
+	TypeBase string   // type 的前缀, 如 https://example.com/errors/, 会拼接错误码; 为空时为 about:blank
+}
+
+// Problem RFC 7807 的响应内容
+type Problem struct {
+	Type     string      `json:"type"`
+	Title    string      `json:"title"`
+	Status   int         `json:"status"`
+	Detail   string      `json:"detail,omitempty"`
+	Instance string      `json:"instance,omitempty"`
+	Code     int64       `json:"code"`
+	Data     interface{} `json:"data,omitempty"`
+}
+
+func (self *ProblemRenderer) Render(ctx *gin.Context, status int, resp *Response) {
+	if resp.Code == ginaerror.OK {
+		success := self.Success
+		if success == nil {
+			success = &EnvelopeRenderer{}
+		}
+		success.Render(ctx, status, resp)
+		return
+	}
+
+	// 错误不能以 2xx 返回, 没有指定状态码时使用错误码对应的状态码
+	if status < http.StatusBadRequest {
+		status = ginaerror.MappedStatus(resp.Code)
+	}
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   resp.Msg,
+		Instance: ctx.Request.URL.Path,
+		Code:     resp.Code,
+		Data:     resp.Data,
+	}
+	if self.TypeBase != "" {
+		problem.Type = self.TypeBase + strconv.FormatInt(resp.Code, 10)
+	}
+	body, err := json.Marshal(problem)
+	if err != nil {
+		Log.WithCtx(ctx).Error("[gina.ProblemRenderer] 序列化响应失败", zap.Error(err))
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	ctx.Data(status, "application/problem+json; charset=utf-8", body)
+}
+
+// 通过代码设置的优先于配置
+var (
+	renderMu       sync.RWMutex
+	renderer       Renderer
+	configRenderer Renderer = &EnvelopeRenderer{}
+	statusMapping  *bool
+	configMapping  bool
+)
+
+// SetRenderer 替换响应的渲染器, 优先于 App.Response 的配置, 为 nil 时恢复使用配置
+func SetRenderer(r Renderer) {
+	renderMu.Lock()
+	defer renderMu.Unlock()
+
+	renderer = r
+}
+
+// SetHTTPStatusMapping 是否按错误码返回真实的 HTTP 状态码, 如 NeedLogin 返回 401, 与 App.Response.HTTPStatus 一致
+func SetHTTPStatusMapping(enabled bool) {
+	renderMu.Lock()
+	defer renderMu.Unlock()
+
+	statusMapping = &enabled
+}
+
+// 当前的渲染器, 以及是否按错误码返回真实的 HTTP 状态码
+func currentRenderer() (Renderer, bool) {
+	renderMu.RLock()
+	defer renderMu.RUnlock()
+
+	r, mapping := renderer, configMapping
+	if r == nil {
+		r = configRenderer
+	}
+	if statusMapping != nil {
+		mapping = *statusMapping
+	}
+
+	return r, mapping
+}
+
+// 按 App.Response 创建渲染器
+func initResponse() {
+	fields := EnvelopeFields{
+		Code:    viper.GetString("App.Response.Fields.Code"),
+		Msg:     viper.GetString("App.Response.Fields.Msg"),
+		Data:    viper.GetString("App.Response.Fields.Data"),
+		NowTime: viper.GetString("App.Response.Fields.NowTime"),
+		UseTime: viper.GetString("App.Response.Fields.UseTime"),
+	}
+	var r Renderer = &EnvelopeRenderer{Fields: fields, OmitTiming: viper.GetBool("App.Response.OmitTiming")}
+	if viper.GetString("App.Response.Mode") == ResponseModeProblem {
+		r = &ProblemRenderer{Success: r, TypeBase: viper.GetString("App.Response.ProblemType")}
+	}
+
+	renderMu.Lock()
+	defer renderMu.Unlock()
+	configRenderer = r
+	configMapping = viper.GetBool("App.Response.HTTPStatus")
+}
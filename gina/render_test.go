@@ -0,0 +1,156 @@
+package gina_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/soryetong/greasyx/gina"
+	"github.com/soryetong/greasyx/ginatest"
+	"github.com/soryetong/greasyx/libs/ginaerror"
+)
+
+// 使用 response 作为 App.Response 启动框架, 请求 handler 并返回状态码、Content-Type 和解析后的响应
+func serve(t *testing.T, response map[string]any, handler gin.HandlerFunc) (int, string, map[string]any) {
+	t.Helper()
+	ginatest.New(t, map[string]any{"App": map[string]any{"Response": response}})
+
+	engine := gin.New()
+	engine.GET("/orders/1", handler)
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+
+	var body map[string]any
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("响应不是 JSON: %s", recorder.Body)
+	}
+
+	return recorder.Code, recorder.Header().Get("Content-Type"), body
+}
+
+func TestRender(t *testing.T) {
+	needLogin := func(ctx *gin.Context) { gina.Fail(ctx, ginaerror.NeedLogin, "请先登录") }
+	code := func(code int64) float64 { return float64(code) }
+
+	tests := []struct {
+		name        string
+		response    map[string]any
+		handler     gin.HandlerFunc
+		wantStatus  int
+		wantProblem bool
+		wantBody    map[string]any // 只比较其中的字段
+		wantMissing []string       // 不应该出现的字段
+	}{
+		{
+			name:       "默认始终返回 200",
+			handler:    needLogin,
+			wantStatus: http.StatusOK,
+			wantBody:   map[string]any{"code": code(ginaerror.NeedLogin), "msg": "请先登录"},
+		},
+		{
+			name:       "按错误码返回真实的状态码",
+			response:   map[string]any{"HTTPStatus": true},
+			handler:    needLogin,
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   map[string]any{"code": code(ginaerror.NeedLogin)},
+		},
+		{
+			name:       "成功时返回 200",
+			response:   map[string]any{"HTTPStatus": true},
+			handler:    func(ctx *gin.Context) { gina.Success(ctx, "ok") },
+			wantStatus: http.StatusOK,
+			wantBody:   map[string]any{"code": code(ginaerror.OK), "data": "ok"},
+		},
+		{
+			name:        "自定义字段名称",
+			response:    map[string]any{"Fields": map[string]any{"Code": "errcode", "Msg": "message"}, "OmitTiming": true},
+			handler:     needLogin,
+			wantStatus:  http.StatusOK,
+			wantBody:    map[string]any{"errcode": code(ginaerror.NeedLogin), "message": "请先登录"},
+			wantMissing: []string{"code", "msg", "nowTime", "useTime"},
+		},
+		{
+			name:        "RFC 7807",
+			response:    map[string]any{"Mode": gina.ResponseModeProblem},
+			handler:     needLogin,
+			wantStatus:  http.StatusUnauthorized,
+			wantProblem: true,
+			wantBody: map[string]any{
+				"type": "about:blank", "title": "Unauthorized", "status": float64(http.StatusUnauthorized),
+				"detail": "请先登录", "instance": "/orders/1", "code": code(ginaerror.NeedLogin),
+			},
+			wantMissing: []string{"msg", "data"},
+		},
+		{
+			name:        "RFC 7807 的 type 拼接错误码",
+			response:    map[string]any{"Mode": gina.ResponseModeProblem, "ProblemType": "https://example.com/errors/"},
+			handler:     needLogin,
+			wantStatus:  http.StatusUnauthorized,
+			wantProblem: true,
+			wantBody:    map[string]any{"type": "https://example.com/errors/" + strconv.FormatInt(ginaerror.NeedLogin, 10)},
+		},
+		{
+			name:     "RFC 7807 指定的状态码优先",
+			response: map[string]any{"Mode": gina.ResponseModeProblem},
+			handler: func(ctx *gin.Context) {
+				gina.Error(ctx, ginaerror.New(ginaerror.HasData, "订单已存在").WithStatus(http.StatusConflict).WithDetails("1"))
+			},
+			wantStatus:  http.StatusConflict,
+			wantProblem: true,
+			wantBody:    map[string]any{"title": "Conflict", "detail": "订单已存在", "data": "1", "code": code(ginaerror.HasData)},
+		},
+		{
+			name:        "RFC 7807 的内部错误",
+			response:    map[string]any{"Mode": gina.ResponseModeProblem},
+			handler:     func(ctx *gin.Context) { gina.Error(ctx, errors.New("dial tcp: connection refused")) },
+			wantStatus:  http.StatusInternalServerError,
+			wantProblem: true,
+			wantBody:    map[string]any{"status": float64(http.StatusInternalServerError), "code": code(ginaerror.ServerError)},
+		},
+		{
+			name:       "RFC 7807 成功的响应不变",
+			response:   map[string]any{"Mode": gina.ResponseModeProblem},
+			handler:    func(ctx *gin.Context) { gina.Success(ctx, "ok") },
+			wantStatus: http.StatusOK,
+			wantBody:   map[string]any{"code": code(ginaerror.OK), "msg": "success", "data": "ok"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, contentType, body := serve(t, tt.response, tt.handler)
+			if status != tt.wantStatus {
+				t.Fatalf("状态码为 %d, 应该为 %d, 响应为 %v", status, tt.wantStatus, body)
+			}
+			if isProblem := contentType == "application/problem+json; charset=utf-8"; isProblem != tt.wantProblem {
+				t.Fatalf("Content-Type 为 %s", contentType)
+			}
+			for key, want := range tt.wantBody {
+				if body[key] != want {
+					t.Fatalf("%s 为 %v, 应该为 %v, 响应为 %v", key, body[key], want, body)
+				}
+			}
+			for _, key := range tt.wantMissing {
+				if _, ok := body[key]; ok {
+					t.Fatalf("响应中不应该有 %s: %v", key, body)
+				}
+			}
+		})
+	}
+}
+
+// 通过 SetRenderer 设置的渲染器优先于配置
+func TestSetRenderer(t *testing.T) {
+	gina.SetRenderer(&gina.ProblemRenderer{TypeBase: "urn:error:"})
+	t.Cleanup(func() { gina.SetRenderer(nil) })
+
+	status, _, body := serve(t, map[string]any{"Mode": gina.ResponseModeEnvelope}, func(ctx *gin.Context) {
+		gina.Fail(ctx, ginaerror.RequestLimit)
+	})
+	if status != http.StatusTooManyRequests || body["type"] != "urn:error:"+strconv.FormatInt(ginaerror.RequestLimit, 10) {
+		t.Fatalf("状态码为 %d, 响应为 %v", status, body)
+	}
+}
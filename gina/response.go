@@ -2,11 +2,13 @@ package gina
 
 import (
 	"fmt"
+	"net/http"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/soryetong/greasyx/ginahelper"
 	"github.com/soryetong/greasyx/libs/ginaerror"
-	"net/http"
-	"time"
+	"go.uber.org/zap"
 )
 
 type PageResult struct {
@@ -25,7 +27,12 @@ type Response struct {
 }
 
 func Result(ctx *gin.Context, code int64, data interface{}, msg string) {
-	resp := Response{
+	result(ctx, 0, code, data, msg)
+}
+
+// status 为 0 时按错误码决定, 开启 App.Response.HTTPStatus 时使用真实的状态码, 否则使用注册的状态码, 默认为 200
+func result(ctx *gin.Context, status int, code int64, data interface{}, msg string) {
+	resp := &Response{
 		Code:    code,
 		Msg:     msg,
 		Data:    data,
@@ -34,7 +41,18 @@ func Result(ctx *gin.Context, code int64, data interface{}, msg string) {
 	if useTime(ctx) != "" {
 		resp.UseTime = useTime(ctx)
 	}
-	ctx.JSON(http.StatusOK, resp)
+
+	r, mapping := currentRenderer()
+	if status == 0 {
+		if mapping {
+			status = ginaerror.MappedStatus(code)
+		} else {
+			status = ginaerror.Status(code)
+		}
+	}
+	// 供 RequestLog 等中间件读取, 不依赖响应的格式
+	ctx.Set("response", resp)
+	r.Render(ctx, status, resp)
 }
 
 func Success(ctx *gin.Context, data interface{}) {
@@ -46,7 +64,7 @@ func SuccessWithMessage(ctx *gin.Context, msg string) {
 }
 
 func FailWithMessage(ctx *gin.Context, msg string) {
-	Result(ctx, ginaerror.Error, nil, msg)
+	Result(ctx, ginaerror.Fail, nil, msg)
 }
 
 func Fail(ctx *gin.Context, code int64, msg ...string) {
@@ -54,10 +72,32 @@ func Fail(ctx *gin.Context, code int64, msg ...string) {
 	if len(msg) > 0 {
 		message = msg[0]
 	} else {
-		message = ginaerror.GetErrorMessage(code)
+		message = ginaerror.GetLocaleMessage(ginaerror.LocaleFromContext(ctx), code)
+	}
+
+	result(ctx, 0, code, nil, message)
+}
+
+// Error 把 logic 返回的错误转为响应, err 为 nil 时返回成功, 未设置提示时按请求的语言返回错误码对应的提示
+// ginaerror.Error 按其中的错误码、HTTP 状态码、提示和 Details 返回, 其他错误一律返回 ServerError, 避免内部错误信息泄露给用户
+// 内部原因记录到日志, ServerError 和 5xx 记录为 Error, 其他有内部原因的记录为 Warn
+func Error(ctx *gin.Context, err error) {
+	if err == nil {
+		Success(ctx, nil)
+		return
+	}
+
+	e := ginaerror.FromError(err)
+	fields := []zap.Field{zap.Int64("code", e.Code), zap.String("path", ctx.Request.URL.Path), zap.Error(e.Cause())}
+	if e.Code == ginaerror.ServerError || e.HTTPStatus() >= http.StatusInternalServerError {
+		Log.WithCtx(ctx).Error("[gina.Error] 请求处理失败", fields...)
+		// 只有系统错误才记录到 span 中
+		_ = ctx.Error(err)
+	} else if e.Cause() != nil {
+		Log.WithCtx(ctx).Warn("[gina.Error] 请求处理失败", fields...)
 	}
 
-	Result(ctx, code, nil, message)
+	result(ctx, e.Status, e.Code, e.Details, e.MsgFor(ginaerror.LocaleFromContext(ctx)))
 }
 
 func useTime(c *gin.Context) string {
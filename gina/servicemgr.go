@@ -1,55 +1,203 @@
 package gina
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/soryetong/greasyx/console"
 	"github.com/soryetong/greasyx/ginahelper"
+	"github.com/soryetong/greasyx/libs/ginagrace"
 	"github.com/spf13/cobra"
-	"golang.org/x/sync/errgroup"
+	"github.com/spf13/viper"
 )
 
 func init() {
 	console.Append(serviceMgrCmd)
 }
 
+// DefaultShutdownTimeout 未配置 App.ShutdownTimeout 时停机的最长等待时间
+const DefaultShutdownTimeout = 30 * time.Second
+
+// ShutdownTimeout 停机的最长等待时间, 所有服务的停机和 SIGHUP 时等待新进程就绪共用这个时间
+func ShutdownTimeout() time.Duration {
+	timeout, err := ginahelper.ToDuration(viper.Get("App.ShutdownTimeout"))
+	if err != nil || timeout <= 0 {
+		return DefaultShutdownTimeout
+	}
+
+	return timeout
+}
+
+// 服务管理器的退出码
+const (
+	ExitCodeOK      = 0   // 正常退出
+	ExitCodeError   = 1   // 服务启动或停机失败
+	ExitCodeTimeout = 124 // 服务未能在 App.ShutdownTimeout 内完成停机
+)
+
 var serviceMgrCmd = &cobra.Command{
 	Use:   "Start", // 命令名称, 不要修改
 	Short: "Web项目的服务启动",
 	Long:  `通过注册你指定的路由启动一个HTTP服务`,
 	Run: func(cmd *cobra.Command, args []string) {
-		defer closeServiceMgr()
 		if len(serviceList) <= 0 {
 			console.Echo.Fatalln("❌ 错误: 请务必通过实现接口 `gina.IService` 注册你要启动的服务")
 		}
 
-		var eg errgroup.Group
-		for _, service := range serviceList {
-			eg.Go(func() error {
-				if err := service.OnStart(); err != nil {
-					err = fmt.Errorf("服务 %s: %v", ginahelper.GetCallerName(service), err)
-					console.Echo.Errorf("❌  错误: %s", err)
+		os.Exit(runServiceMgr())
+	},
+}
+
+// 启动所有服务, 收到退出信号或任一服务异常退出后, 按注册顺序的逆序停止服务并释放资源
+// 收到 SIGHUP 时先启动新进程接管所有监听, 新进程就绪后再按同样的方式停止当前进程
+func runServiceMgr() int {
+	defer closeServiceMgr()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(quit)
+
+	failed := make(chan error, len(serviceList))
+	doneList := make([]chan struct{}, len(serviceList))
+	startedList := trackStarted()
+	var startFailed atomic.Bool
+	for i, service := range serviceList {
+		doneList[i] = make(chan struct{})
+		go func() {
+			defer close(doneList[i])
+			if err := service.OnStart(); err != nil {
+				startFailed.Store(true)
+				err = fmt.Errorf("服务 %s: %v", ginahelper.GetCallerName(service), err)
+				console.Echo.Errorf("❌  错误: %s", err)
+				failed <- err
+			}
+		}()
+	}
+
+	// 所有服务都启动完成后才通知父进程, 有服务启动失败时不通知, 父进程等待超时后继续提供服务
+	ginahelper.SafeGo(func() {
+		for i := range serviceList {
+			select {
+			case <-startedList[i]:
+			case <-doneList[i]:
+			}
+		}
+		if !startFailed.Load() {
+			ginagrace.Ready()
+		}
+	})
+
+	allDone := make(chan struct{})
+	ginahelper.SafeGo(func() {
+		for _, done := range doneList {
+			<-done
+		}
+		close(allDone)
+	})
 
-					return err
+	exitCode := ExitCodeOK
+wait:
+	for {
+		select {
+		case sig := <-quit:
+			if sig == syscall.SIGHUP {
+				if !restart() {
+					continue
 				}
+				break wait
+			}
+			console.Echo.Infof("ℹ️ 提示: 收到信号 %s, 开始停止所有服务\n", sig)
+			break wait
+		case <-failed:
+			exitCode = ExitCodeError
+			break wait
+		case <-allDone:
+			return ExitCodeOK
+		}
+	}
 
-				return nil
-			})
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout())
+	defer cancel()
+	ginahelper.SafeGo(func() {
+		for {
+			select {
+			case sig := <-quit:
+				if sig == syscall.SIGHUP {
+					continue
+				}
+				console.Echo.Warnf("⚠️ 警告: 停机过程中再次收到信号 %s, 强制退出\n", sig)
+				os.Exit(ExitCodeError)
+			case <-ctx.Done():
+				return
+			}
 		}
+	})
 
-		// 等待所有任务完成
-		_ = eg.Wait()
-		os.Exit(124)
-	},
+	if code := stopServices(ctx, doneList); exitCode == ExitCodeOK {
+		exitCode = code
+	}
+
+	return exitCode
+}
+
+// 启动新进程并等待它就绪, 最多等待 App.ShutdownTimeout, 失败时当前进程继续提供服务
+func restart() bool {
+	console.Echo.Infof("ℹ️ 提示: 收到信号 SIGHUP, 开始重启\n")
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout())
+	defer cancel()
+	if err := ginagrace.Restart(ctx); err != nil {
+		console.Echo.Errorf("❌  错误: 重启失败, 继续使用当前进程提供服务: %s", err)
+		return false
+	}
+
+	console.Echo.Infof("✅ 提示: 新进程已就绪, 开始停止当前进程\n")
+	return true
+}
+
+// 按注册顺序的逆序停止服务, 所有服务共享同一个停机截止时间
+func stopServices(ctx context.Context, doneList []chan struct{}) int {
+	exitCode := ExitCodeOK
+	for i := len(serviceList) - 1; i >= 0; i-- {
+		service := serviceList[i]
+		name := ginahelper.GetCallerName(service)
+		stop := defaultStop(service)
+		if stopper, ok := service.(IStopper); ok {
+			stop = stopper.OnStop
+		} else if stop == nil {
+			console.Echo.Warnf("⚠️ 警告: 服务 %s 未实现 `gina.IStopper`, 也没有启动 IHttp 或 IGrpc, 无法优雅停机\n", name)
+			continue
+		}
+
+		if err := stop(ctx); err != nil {
+			console.Echo.Errorf("❌  错误: 服务 %s 停机失败: %s", name, err)
+			exitCode = ExitCodeError
+		}
+
+		select {
+		case <-doneList[i]:
+			console.Echo.Infof("✅ 提示: 服务 %s 已停止\n", name)
+		case <-ctx.Done():
+			console.Echo.Errorf("❌  错误: 服务 %s 未能在 %s 内完成停机", name, ShutdownTimeout())
+			return ExitCodeTimeout
+		}
+	}
+
+	return exitCode
 }
 
+// 释放资源的失败已经在 CloseModules 中逐个记录
 func closeServiceMgr() {
+	_ = console.CloseModules()
 	_ = console.Echo.Sync()
-	_ = Log.Sync()
-	if rotationScheduler != nil {
-		rotationScheduler.Stop()
-	}
 }
 
 var serviceList []IService
@@ -62,6 +210,91 @@ type IService interface {
 	OnStart() error
 }
 
+// 各服务是否已经启动完成, 与 serviceList 一一对应, 服务管理器启动后才会创建
+var (
+	startedMu   sync.Mutex
+	startedList []chan struct{}
+)
+
+func trackStarted() []chan struct{} {
+	startedMu.Lock()
+	defer startedMu.Unlock()
+
+	startedList = make([]chan struct{}, len(serviceList))
+	for i := range startedList {
+		startedList[i] = make(chan struct{})
+	}
+
+	return startedList
+}
+
+// Started 通知服务管理器服务已经启动完成, OnStart 会一直阻塞的服务需要在完成监听、订阅等准备工作后调用
+// IHttp、IGrpc 完成监听后会自动调用; 通过 SIGHUP 重启时, 新进程的所有服务都启动完成后才会通知旧进程退出
+// service 为通过 Register 注册的服务, 重复调用或者服务未注册时不做任何事情
+func Started(service any) {
+	if service == nil || !reflect.TypeOf(service).Comparable() {
+		return
+	}
+
+	startedMu.Lock()
+	defer startedMu.Unlock()
+	for i, registered := range serviceList {
+		if i >= len(startedList) || any(registered) != service {
+			continue
+		}
+		select {
+		case <-startedList[i]:
+		default:
+			close(startedList[i])
+		}
+	}
+}
+
+// IStopper 服务可选实现的停机接口, 停机时按注册顺序的逆序调用, ctx 在 App.ShutdownTimeout 后到期
+type IStopper interface {
+	OnStop(ctx context.Context) error
+}
+
+// 服务在 OnStart 中启动的 IHttp、IGrpc 等的停机函数, 服务未实现 IStopper 时停机时逐个调用
+var (
+	attachedMu sync.Mutex
+	attached   = make(map[any][]func(ctx context.Context) error)
+)
+
+// AttachStopper 为服务添加默认的停机函数, IHttp 和 IGrpc 在 Init 时自动添加, 服务实现了 IStopper 时不会调用
+// service 一般为 Init 时传入的 caller, 即服务本身
+func AttachStopper(service any, stop func(ctx context.Context) error) {
+	if service == nil || !reflect.TypeOf(service).Comparable() {
+		return
+	}
+
+	attachedMu.Lock()
+	defer attachedMu.Unlock()
+	attached[service] = append(attached[service], stop)
+}
+
+// 按添加的逆序停止服务启动的 IHttp、IGrpc 等, 没有添加任何停机函数时返回 nil
+func defaultStop(service IService) func(ctx context.Context) error {
+	if !reflect.TypeOf(service).Comparable() {
+		return nil
+	}
+
+	attachedMu.Lock()
+	stops := slices.Clone(attached[service])
+	attachedMu.Unlock()
+	if len(stops) == 0 {
+		return nil
+	}
+
+	return func(ctx context.Context) error {
+		var errs []error
+		for i := len(stops) - 1; i >= 0; i-- {
+			errs = append(errs, stops[i](ctx))
+		}
+		return errors.Join(errs...)
+	}
+}
+
 type IServer struct {
 	IService
 }
@@ -2,9 +2,11 @@ package ginahelper
 
 import (
 	"encoding/json"
+	"fmt"
 	"reflect"
 	"regexp"
 	"strconv"
+	"time"
 )
 
 func StringToInt64(s string) (i int64) {
@@ -119,3 +121,31 @@ func InterfaceToInt64(inVal interface{}) int64 {
 
 	return 0
 }
+
+// ToDuration 把配置中的时间转换为 time.Duration, 字符串按 time.ParseDuration 解析, 如 "30s"、"1m30s"
+// 数字以及只有数字的字符串按秒处理, 如 30、1.5, 兼容以前以秒为单位的配置
+func ToDuration(value interface{}) (time.Duration, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case time.Duration:
+		return v, nil
+	case int:
+		return time.Duration(v) * time.Second, nil
+	case int64:
+		return time.Duration(v) * time.Second, nil
+	case float64:
+		return time.Duration(v * float64(time.Second)), nil
+	case string:
+		if seconds, err := strconv.ParseFloat(v, 64); err == nil {
+			return time.Duration(seconds * float64(time.Second)), nil
+		}
+		duration, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, fmt.Errorf("%q 不是合法的时间, 如 30s、1m30s", v)
+		}
+		return duration, nil
+	default:
+		return 0, fmt.Errorf("%v 不是合法的时间, 如 30s、1m30s", v)
+	}
+}
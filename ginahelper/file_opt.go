@@ -15,8 +15,14 @@ import (
 	"time"
 )
 
+// Deprecated: 使用 JoinServerPath, 同一进程有多个服务时 GetServerAddr 只是最后一个启动的服务的地址
 func AssembleServerPath(filepath string) string {
-	return strings.TrimRight(GetServerAddr(), "/") + "/" + strings.TrimLeft(filepath, "/")
+	return JoinServerPath(GetServerAddr(), filepath)
+}
+
+// JoinServerPath 拼接访问地址和文件路径, baseUrl 一般为 IHttp.URL() 或者配置中的地址
+func JoinServerPath(baseUrl, filepath string) string {
+	return strings.TrimRight(baseUrl, "/") + "/" + strings.TrimLeft(filepath, "/")
 }
 
 // 目录是否为空
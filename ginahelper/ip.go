@@ -7,6 +7,20 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// IsLoopback addr 是否为 localhost 或者回环地址, 如 127.0.0.1:8080, 未指定 host 时监听所有地址, 不属于本机地址
+func IsLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
+
 func GetLocalIP() string {
 	interfaces, err := net.Interfaces()
 	if err != nil {
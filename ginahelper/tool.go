@@ -9,6 +9,8 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
+// Deprecated: 一个进程中可以有多个服务, 每个服务可以有多个监听, 使用 httpmodule.IHttp 的 URL 和 Listeners
+// 为了兼容, 仍然是最后一个通过 Start/StartTLS 启动的服务在 Init 时传入的地址
 var (
 	ServerAddr  string
 	ServerIsTLS bool
@@ -71,6 +73,7 @@ func GetBrowser(userAgent string) string {
 	return "Unknown"
 }
 
+// Deprecated: 使用 httpmodule.IHttp 的 URL
 func GetServerAddr() string {
 	prefix := "http"
 	if ServerIsTLS {
@@ -0,0 +1,152 @@
+// Package ginatest 在测试进程内启动 Greasyx, 不经过命令行, 用于编写集成测试
+//
+// 框架的资源都保存在 gina 的全局变量中, 所以使用 ginatest 的测试不能并行执行
+package ginatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/soryetong/greasyx/console"
+	"github.com/soryetong/greasyx/gina"
+	"github.com/soryetong/greasyx/libs/ginaauth"
+	"github.com/soryetong/greasyx/modules/dbmodule"
+	"github.com/soryetong/greasyx/modules/redismodule"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// Env 测试环境, 测试结束时自动释放所有资源
+type Env struct {
+	T      testing.TB
+	LogDir string // 日志目录, 测试结束后自动删除
+
+	redis *miniredis.Miniredis
+}
+
+// New 使用 settings 作为配置启动框架, 日志写入临时目录, 未配置的项使用测试友好的默认值
+func New(t testing.TB, settings map[string]any) *Env {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	env := &Env{T: t, LogDir: t.TempDir()}
+	v := viper.New()
+	_ = v.MergeConfigMap(map[string]any{
+		"App": map[string]any{"Env": "test"},
+		"Log": map[string]any{"Path": env.LogDir, "Mode": "file", "Logrotate": false},
+	})
+	if err := v.MergeConfigMap(settings); err != nil {
+		t.Fatalf("ginatest: 合并配置失败: %s", err)
+	}
+	if err := gina.SetupWithConfig(v.AllSettings()); err != nil {
+		t.Fatalf("ginatest: 启动框架失败: %s", err)
+	}
+	t.Cleanup(func() {
+		if err := console.CloseModules(); err != nil {
+			t.Errorf("ginatest: 释放资源失败: %s", err)
+		}
+	})
+
+	return env
+}
+
+// DB 创建内存中的 SQLite 数据库, 并通过 gina.SetGorm 注册为 driver, driver 为空时注册为 sqlite
+func (self *Env) DB(driver string) *gorm.DB {
+	self.T.Helper()
+	if driver == "" {
+		driver = gina.DbTypeSqlite
+	}
+
+	// 使用共享缓存, 连接池中的所有连接访问同一个数据库
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.NewReplacer("/", "_", " ", "_").Replace(self.T.Name()+"_"+driver))
+	db, err := dbmodule.Open(&dbmodule.Config{Driver: gina.DbTypeSqlite, Dsn: dsn, UseOrm: true, LogLevel: 1})
+	if err != nil {
+		self.T.Fatalf("ginatest: 创建 SQLite 数据库失败: %s", err)
+	}
+
+	gina.SetGorm(driver, db)
+	self.T.Cleanup(func() {
+		gina.SetGorm(driver, nil)
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+
+	return db
+}
+
+// Redis 在进程内启动兼容 Redis 协议的服务, 并赋值给 gina.Rdb, 多次调用返回同一个服务
+func (self *Env) Redis() *miniredis.Miniredis {
+	self.T.Helper()
+	if self.redis != nil {
+		return self.redis
+	}
+
+	self.redis = miniredis.RunT(self.T)
+	client, err := redismodule.New(&redismodule.Options{Addr: self.redis.Addr()})
+	if err != nil {
+		self.T.Fatalf("ginatest: 连接 Redis 失败: %s", err)
+	}
+
+	gina.Rdb = client
+	self.T.Cleanup(func() {
+		gina.Rdb = nil
+		_ = client.Close()
+	})
+
+	return self.redis
+}
+
+// Serve 使用 handler 启动 httptest.Server, 一般传入 gin.Engine 或者 httpmodule.IHttp
+func (self *Env) Serve(handler http.Handler) *httptest.Server {
+	srv := httptest.NewServer(handler)
+	self.T.Cleanup(srv.Close)
+
+	return srv
+}
+
+// Token 通过 ginaauth 生成 Token, 返回的值可以直接作为 Authorization 请求头
+func (self *Env) Token(claims jwt.MapClaims) string {
+	self.T.Helper()
+	token, err := ginaauth.GenerateJwtToken(claims)
+	if err != nil {
+		self.T.Fatalf("ginatest: 生成 Token 失败: %s", err)
+	}
+
+	return "Bearer " + token
+}
+
+// DecodeResponse 解析 gina.Response 格式的响应, data 不为空时同时将 Data 解析到 data 中
+func DecodeResponse(t testing.TB, resp *http.Response, data any) *gina.Response {
+	t.Helper()
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("ginatest: 读取响应失败: %s", err)
+	}
+
+	var raw struct {
+		gina.Response
+		Data json.RawMessage `json:"data"`
+	}
+	if err = json.Unmarshal(body, &raw); err != nil {
+		t.Fatalf("ginatest: 响应不是 gina.Response 格式: %s, 内容为: %s", err, body)
+	}
+	if data != nil && len(raw.Data) > 0 {
+		if err = json.Unmarshal(raw.Data, data); err != nil {
+			t.Fatalf("ginatest: 解析 Data 失败: %s, 内容为: %s", err, raw.Data)
+		}
+	}
+	raw.Response.Data = data
+
+	return &raw.Response
+}
@@ -3,16 +3,50 @@ package ginaauth
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/soryetong/greasyx/console"
+	"github.com/soryetong/greasyx/gina"
 	"github.com/soryetong/greasyx/ginahelper"
 	"github.com/spf13/viper"
 )
 
 var secretKey []byte
 
+// Token 的有效期, 单位是秒, 支持通过配置热更新
+var (
+	expire     atomic.Int64
+	expireOnce sync.Once
+)
+
+type jwtConfig struct {
+	Expire int64
+}
+
+func init() {
+	gina.OnConfigChange("Jwt", func(old, new jwtConfig) {
+		expireOnce.Do(func() {})
+		expire.Store(new.Expire)
+		console.Echo.Infof("✅ 提示: Jwt.Expire 已更新为 %d 秒\n", new.Expire)
+	}, func(new jwtConfig) error {
+		if new.Expire < 0 {
+			return fmt.Errorf("Jwt.Expire 不能小于 0")
+		}
+		return nil
+	})
+}
+
+func getExpire() int64 {
+	expireOnce.Do(func() {
+		expire.Store(viper.GetInt64("Jwt.Expire"))
+	})
+
+	return expire.Load()
+}
+
 func getSecretKey() []byte {
 	if len(secretKey) == 0 {
 		secretKey = loadSecretKey()
@@ -40,7 +74,7 @@ func GenerateJwtToken(claimsMap jwt.MapClaims) (string, error) {
 	claimsMap["iat"] = time.Now().Unix()
 	claimsMap["nbf"] = time.Now().Unix()
 	if _, ok := claimsMap["exp"]; !ok {
-		expire := viper.GetInt64("Jwt.Expire")
+		expire := getExpire()
 		if expire > 0 {
 			claimsMap["exp"] = time.Now().Add(time.Duration(expire) * time.Second).Unix()
 		} else {
@@ -0,0 +1,136 @@
+package ginaerror
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 内置的错误码提示, 文件名为语言, 内容为错误码到提示的映射
+//
+//go:embed locales/*.json
+var builtinCatalogs embed.FS
+
+var (
+	catalogMu sync.RWMutex
+	catalogs  = make(map[string]map[int64]string) // locale => code => message
+)
+
+func init() {
+	entries, _ := builtinCatalogs.ReadDir("locales")
+	for _, entry := range entries {
+		data, _ := builtinCatalogs.ReadFile("locales/" + entry.Name())
+		if err := loadCatalogData(entry.Name(), data); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterMessages 添加或覆盖某个语言的错误码提示, 一般用于注册自定义的错误码
+func RegisterMessages(locale string, messages map[int64]string) {
+	locale = NormalizeLocale(locale)
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+
+	if catalogs[locale] == nil {
+		catalogs[locale] = make(map[int64]string, len(messages))
+	}
+	for code, message := range messages {
+		catalogs[locale][code] = message
+	}
+}
+
+// LoadCatalogDir 加载目录下所有语言的错误码提示, 文件名为语言, 如 en.json、ja.yaml, 内容为 {"1004": "No data found"}
+// 与内置的提示合并, 相同的错误码会覆盖内置的提示
+func LoadCatalogDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("读取错误码目录 %s 失败: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		if err = loadCatalogData(entry.Name(), data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func loadCatalogData(filename string, data []byte) error {
+	ext := filepath.Ext(filename)
+	raw := make(map[string]string)
+	var err error
+	switch ext {
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("错误码文件 %s 格式错误: %w", filename, err)
+	}
+
+	messages := make(map[int64]string, len(raw))
+	for key, message := range raw {
+		code, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return fmt.Errorf("错误码文件 %s 中的错误码 %s 不是数字", filename, key)
+		}
+		messages[code] = message
+	}
+	RegisterMessages(strings.TrimSuffix(filename, ext), messages)
+
+	return nil
+}
+
+// Locales 已加载错误码提示的语言
+func Locales() []string {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	locales := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+
+	return locales
+}
+
+func hasCatalog(locale string) bool {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// 依次从指定语言、默认语言和中文中查找, 都没有时返回 ok 为 false
+func lookupMessage(locale string, code int64) (string, bool) {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	for _, l := range []string{locale, DefaultLocale(), LocaleZh} {
+		if message, ok := catalogs[l][code]; ok {
+			return message, true
+		}
+	}
+
+	return "", false
+}
@@ -3,7 +3,7 @@ package ginaerror
 // 1开头系统校验类,2开头用户及用户行为校验类
 const (
 	OK                   = 200  // 通用-Success
-	Error                = 400  // 通用-ERROR
+	Fail                 = 400  // 通用-ERROR
 	ServerError          = 500  // 系统错误
 	ParameterIllegal     = 1002 // 参数不合法
 	NoAuth               = 1003 // 权限不足
@@ -22,38 +22,21 @@ const (
 	UserIsset            = 2005 // 用户已存在
 )
 
+// GetErrorMessage 错误码在默认语言下的提示, 传入 message 时直接返回 message
 func GetErrorMessage(code int64, message ...string) string {
+	return GetLocaleMessage(DefaultLocale(), code, message...)
+}
+
+// GetLocaleMessage 错误码在指定语言下的提示, 依次从指定语言、默认语言和中文中查找, 都没有时返回系统错误的提示
+func GetLocaleMessage(locale string, code int64, message ...string) string {
 	if len(message) > 0 {
 		return message[0]
 	}
 
-	var codeMessage string
-	codeMap := map[int64]string{
-		OK:                   "Success",
-		Error:                "请求错误",
-		ServerError:          "系统错误",
-		ParameterIllegal:     "参数不合法",
-		NoAuth:               "权限不足",
-		NotData:              "没有数据",
-		HasData:              "数据已存在",
-		UnauthorizedToken:    "非法的用户token",
-		NeedLogin:            "请先登录",
-		RequestLimit:         "请求频繁,请稍后再试",
-		CaptchaGenerateError: "验证码生成错误",
-		CaptchaError:         "验证码错误",
-		LoginFail:            "登录失败",
-		LoginPasswordError:   "密码错误",
-		LoginNoUser:          "该用户不存在",
-		LoginBan:             "你暂时不能进行登录操作",
-		ThirdLoginError:      "第三方登录失败",
-		UserIsset:            "用户已存在",
-	}
-
-	if value, ok := codeMap[code]; ok {
-		codeMessage = value
-	} else {
-		codeMessage = "系统错误!"
+	if codeMessage, ok := lookupMessage(locale, code); ok {
+		return codeMessage
 	}
+	codeMessage, _ := lookupMessage(locale, ServerError)
 
 	return codeMessage
 }
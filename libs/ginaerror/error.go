@@ -0,0 +1,127 @@
+package ginaerror
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error 业务错误, 由 logic 返回, 经过 gina.Error 转为响应
+// Message 返回给用户, cause 为内部原因, 只记录到日志, 不会返回给用户
+type Error struct {
+	Code    int64       // 业务错误码
+	Status  int         // HTTP 状态码, 为 0 时使用错误码注册的状态码, 与 gina.Fail 一致
+	Message string      // 返回给用户的提示, 为空时使用错误码对应的提示
+	Details interface{} // 额外的数据, 作为响应的 data 返回, 如字段的校验错误
+
+	cause error
+}
+
+// New 以错误码创建业务错误, 未传入 message 时使用错误码对应的提示
+func New(code int64, message ...string) *Error {
+	err := &Error{Code: code}
+	if len(message) > 0 {
+		err.Message = message[0]
+	}
+
+	return err
+}
+
+// Newf 以格式化的提示创建业务错误
+func Newf(code int64, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap 以错误码包装内部错误, err 为 nil 时返回 nil, 方便直接 return ginaerror.Wrap(err, ...)
+// 内部错误只记录到日志, 返回给用户的是 message 或错误码对应的提示
+func Wrap(err error, code int64, message ...string) error {
+	if err == nil {
+		return nil
+	}
+
+	wrapped := New(code, message...)
+	wrapped.cause = err
+
+	return wrapped
+}
+
+// WithStatus 设置 HTTP 状态码
+func (self *Error) WithStatus(status int) *Error {
+	self.Status = status
+	return self
+}
+
+// WithDetails 设置额外的数据
+func (self *Error) WithDetails(details interface{}) *Error {
+	self.Details = details
+	return self
+}
+
+// WithCause 设置内部原因
+func (self *Error) WithCause(err error) *Error {
+	self.cause = err
+	return self
+}
+
+func (self *Error) Error() string {
+	if self.cause != nil {
+		return fmt.Sprintf("[%d] %s: %s", self.Code, self.Msg(), self.cause)
+	}
+
+	return fmt.Sprintf("[%d] %s", self.Code, self.Msg())
+}
+
+func (self *Error) Unwrap() error {
+	return self.cause
+}
+
+// Is 错误码相同时认为是同一个错误, 如 errors.Is(err, ginaerror.New(ginaerror.NotData))
+func (self *Error) Is(target error) bool {
+	var other *Error
+	if !errors.As(target, &other) {
+		return false
+	}
+
+	return self.Code == other.Code
+}
+
+// Cause 内部原因, 没有时返回 nil
+func (self *Error) Cause() error {
+	return self.cause
+}
+
+// Msg 返回给用户的提示, 未设置 Message 时使用默认语言
+func (self *Error) Msg() string {
+	return self.MsgFor(DefaultLocale())
+}
+
+// MsgFor 指定语言下返回给用户的提示, 设置了 Message 时直接返回 Message
+func (self *Error) MsgFor(locale string) string {
+	if self.Message != "" {
+		return self.Message
+	}
+
+	return GetLocaleMessage(locale, self.Code)
+}
+
+// HTTPStatus 响应的 HTTP 状态码, 未设置时使用错误码注册的状态码, 都没有时为 200
+func (self *Error) HTTPStatus() int {
+	if self.Status == 0 {
+		return Status(self.Code)
+	}
+
+	return self.Status
+}
+
+// FromError 取出错误链中的业务错误, 不是业务错误时转为 ServerError, 原来的错误作为内部原因
+func FromError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	var target *Error
+	if errors.As(err, &target) {
+		return target
+	}
+
+	return &Error{Code: ServerError, cause: err}
+}
@@ -0,0 +1,140 @@
+package ginaerror
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 内置的语言, 校验错误和错误码提示都支持这些语言
+const (
+	LocaleZh     = "zh"      // 简体中文
+	LocaleEn     = "en"      // 英文
+	LocaleZhHant = "zh_Hant" // 繁体中文
+)
+
+// LocaleQueryKey 请求中指定语言的查询参数, 如 ?lang=en
+const LocaleQueryKey = "lang"
+
+var defaultLocale atomic.Value
+
+func init() {
+	defaultLocale.Store(LocaleZh)
+}
+
+// SetDefaultLocale 设置默认语言, 请求没有指定语言或指定的语言不支持时使用, 默认为 zh
+func SetDefaultLocale(locale string) {
+	if locale = NormalizeLocale(locale); locale != "" {
+		defaultLocale.Store(locale)
+	}
+}
+
+// DefaultLocale 默认语言
+func DefaultLocale() string {
+	return defaultLocale.Load().(string)
+}
+
+// WithLocale 在 ctx 中指定语言, 优先级高于请求中的语言
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, "locale", NormalizeLocale(locale))
+}
+
+// LocaleFromContext 当前请求的语言, 依次从 ctx 中的 locale、查询参数 lang、Token 中的 locale 和 Accept-Language 中读取
+// 只返回已加载错误码提示的语言, 都没有时返回默认语言; gin.Context 的结果会保存到 locale 中, 同一个请求只解析一次
+func LocaleFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return DefaultLocale()
+	}
+	if locale, ok := ctx.Value("locale").(string); ok && hasCatalog(locale) {
+		return locale
+	}
+
+	ginCtx, _ := ctx.(*gin.Context)
+	locale := resolveLocale(ctx, ginCtx)
+	if ginCtx != nil {
+		ginCtx.Set("locale", locale)
+	}
+
+	return locale
+}
+
+func resolveLocale(ctx context.Context, ginCtx *gin.Context) string {
+	if ginCtx != nil && ginCtx.Request != nil {
+		if locale := NormalizeLocale(ginCtx.Query(LocaleQueryKey)); hasCatalog(locale) {
+			return locale
+		}
+	}
+	if claims, ok := ctx.Value("claims").(map[string]interface{}); ok {
+		if value, ok := claims["locale"].(string); ok {
+			if locale := NormalizeLocale(value); hasCatalog(locale) {
+				return locale
+			}
+		}
+	}
+	if ginCtx != nil && ginCtx.Request != nil {
+		if locale := ParseAcceptLanguage(ginCtx.GetHeader("Accept-Language")); locale != "" {
+			return locale
+		}
+	}
+
+	return DefaultLocale()
+}
+
+// ParseAcceptLanguage 按权重返回 Accept-Language 中第一个已加载错误码提示的语言, 没有时返回空字符串
+func ParseAcceptLanguage(header string) string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+
+	var items []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > 0 {
+			items = append(items, weighted{locale: NormalizeLocale(tag), q: q})
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].q > items[j].q
+	})
+
+	for _, item := range items {
+		if hasCatalog(item.locale) {
+			return item.locale
+		}
+	}
+
+	return ""
+}
+
+// NormalizeLocale 把语言标签转为错误码提示使用的语言, 中文按字形分为 zh 和 zh_Hant, 其他语言只保留语言部分
+// 如 zh-CN、zh-Hans 转为 zh, zh-TW、zh-HK、zh-Hant 转为 zh_Hant, en-US 转为 en
+func NormalizeLocale(tag string) string {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "-", "_"))
+	lang, region, _ := strings.Cut(tag, "_")
+	if lang != "zh" {
+		return lang
+	}
+
+	switch {
+	case strings.HasPrefix(region, "hant"), region == "tw", region == "hk", region == "mo":
+		return LocaleZhHant
+	default:
+		return LocaleZh
+	}
+}
@@ -0,0 +1,113 @@
+package ginaerror
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNormalizeLocale(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{tag: "zh", want: LocaleZh},
+		{tag: "zh-CN", want: LocaleZh},
+		{tag: "zh-Hans", want: LocaleZh},
+		{tag: "zh-Hans-TW", want: LocaleZh},
+		{tag: "zh-TW", want: LocaleZhHant},
+		{tag: "zh-HK", want: LocaleZhHant},
+		{tag: "zh_MO", want: LocaleZhHant},
+		{tag: "zh-Hant", want: LocaleZhHant},
+		{tag: "zh-Hant-CN", want: LocaleZhHant},
+		{tag: " ZH-tw ", want: LocaleZhHant},
+		{tag: "en-US", want: LocaleEn},
+		{tag: "EN", want: LocaleEn},
+		{tag: "ja-JP", want: "ja"},
+		{tag: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			if got := NormalizeLocale(tt.tag); got != tt.want {
+				t.Fatalf("NormalizeLocale(%q) = %q, 应该为 %q", tt.tag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: ""},
+		{header: "en-US,en;q=0.9", want: LocaleEn},
+		{header: "zh-TW,zh;q=0.9,en;q=0.8", want: LocaleZhHant},
+		{header: "en;q=0.5, zh-CN;q=0.8", want: LocaleZh},
+		{header: "ja-JP,en;q=0.7", want: LocaleEn},
+		{header: "ja-JP,fr;q=0.7", want: ""},
+		{header: "zh;q=0, en;q=0.1", want: LocaleEn},
+		{header: "*,en;q=0.1", want: LocaleEn},
+		{header: "en;level=1;q=0.1, zh;q=0.5", want: LocaleZh},
+		{header: "en;q=abc, zh;q=0.5", want: LocaleEn},
+		{header: "en, zh", want: LocaleEn},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := ParseAcceptLanguage(tt.header); got != tt.want {
+				t.Fatalf("ParseAcceptLanguage(%q) = %q, 应该为 %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocaleFromContext(t *testing.T) {
+	tests := []struct {
+		name           string
+		ctx            func() context.Context
+		query          string
+		acceptLanguage string
+		claims         string
+		want           string
+	}{
+		{name: "默认语言", want: LocaleZh},
+		{name: "Accept-Language", acceptLanguage: "en-US", want: LocaleEn},
+		{name: "Token 优先于 Accept-Language", claims: "zh-TW", acceptLanguage: "en-US", want: LocaleZhHant},
+		{name: "查询参数优先于 Token", query: "en", claims: "zh-TW", want: LocaleEn},
+		{name: "不支持的查询参数", query: "ja", acceptLanguage: "en", want: LocaleEn},
+		{
+			name: "WithLocale 优先于请求",
+			ctx: func() context.Context {
+				return WithLocale(context.Background(), "zh-HK")
+			},
+			want: LocaleZhHant,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.ctx != nil {
+				if got := LocaleFromContext(tt.ctx()); got != tt.want {
+					t.Fatalf("语言为 %s, 应该为 %s", got, tt.want)
+				}
+				return
+			}
+
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest("GET", "/?"+LocaleQueryKey+"="+tt.query, nil)
+			if tt.acceptLanguage != "" {
+				ctx.Request.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			if tt.claims != "" {
+				ctx.Set("claims", map[string]interface{}{"locale": tt.claims})
+			}
+			if got := LocaleFromContext(ctx); got != tt.want {
+				t.Fatalf("语言为 %s, 应该为 %s", got, tt.want)
+			}
+			if cached, _ := ctx.Get("locale"); cached != tt.want {
+				t.Fatalf("保存的语言为 %v", cached)
+			}
+		})
+	}
+}
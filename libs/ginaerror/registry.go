@@ -0,0 +1,160 @@
+package ginaerror
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// CodeInfo 错误码的定义, 用于导出错误码表
+type CodeInfo struct {
+	Code     int64             `json:"code"`
+	Name     string            `json:"name,omitempty"`     // 常量名, 只有内置的错误码才有
+	Status   int               `json:"status"`             // 未指定 HTTP 状态码时为 200
+	Mapped   int               `json:"mapped_status"`      // 开启 App.Response.HTTPStatus 或使用 RFC 7807 时的 HTTP 状态码
+	Builtin  bool              `json:"builtin"`            // 是否为内置的错误码
+	Messages map[string]string `json:"messages,omitempty"` // locale => message
+}
+
+type codeEntry struct {
+	name    string
+	status  int
+	mapped  int // 内置错误码对应的真实 HTTP 状态码
+	builtin bool
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[int64]codeEntry{
+		OK:                   {name: "OK", mapped: http.StatusOK, builtin: true},
+		Fail:                 {name: "Fail", mapped: http.StatusBadRequest, builtin: true},
+		ServerError:          {name: "ServerError", mapped: http.StatusInternalServerError, builtin: true},
+		ParameterIllegal:     {name: "ParameterIllegal", mapped: http.StatusBadRequest, builtin: true},
+		NoAuth:               {name: "NoAuth", mapped: http.StatusForbidden, builtin: true},
+		NotData:              {name: "NotData", mapped: http.StatusNotFound, builtin: true},
+		HasData:              {name: "HasData", mapped: http.StatusConflict, builtin: true},
+		UnauthorizedToken:    {name: "UnauthorizedToken", mapped: http.StatusUnauthorized, builtin: true},
+		NeedLogin:            {name: "NeedLogin", mapped: http.StatusUnauthorized, builtin: true},
+		RequestLimit:         {name: "RequestLimit", mapped: http.StatusTooManyRequests, builtin: true},
+		CaptchaGenerateError: {name: "CaptchaGenerateError", mapped: http.StatusInternalServerError, builtin: true},
+		CaptchaError:         {name: "CaptchaError", mapped: http.StatusBadRequest, builtin: true},
+		LoginFail:            {name: "LoginFail", mapped: http.StatusUnauthorized, builtin: true},
+		LoginPasswordError:   {name: "LoginPasswordError", mapped: http.StatusUnauthorized, builtin: true},
+		LoginNoUser:          {name: "LoginNoUser", mapped: http.StatusNotFound, builtin: true},
+		LoginBan:             {name: "LoginBan", mapped: http.StatusForbidden, builtin: true},
+		ThirdLoginError:      {name: "ThirdLoginError", mapped: http.StatusUnauthorized, builtin: true},
+		UserIsset:            {name: "UserIsset", mapped: http.StatusConflict, builtin: true},
+	}
+)
+
+// Register 注册自定义的错误码, messages 为各语言的提示, 如 {"zh": "订单已关闭", "en": "Order closed"}
+// httpStatus 为返回该错误码时的 HTTP 状态码, 为 0 时使用 200; 错误码与内置或已注册的错误码重复时返回错误
+func Register(code int64, messages map[string]string, httpStatus int) error {
+	if httpStatus != 0 && (httpStatus < 100 || httpStatus > 599) {
+		return fmt.Errorf("错误码 %d 的 HTTP 状态码 %d 不合法", code, httpStatus)
+	}
+
+	registryMu.Lock()
+	if entry, ok := registry[code]; ok {
+		registryMu.Unlock()
+		if entry.builtin {
+			return fmt.Errorf("错误码 %d 与内置错误码 %s 重复", code, entry.name)
+		}
+		return fmt.Errorf("错误码 %d 已注册", code)
+	}
+	registry[code] = codeEntry{status: httpStatus}
+	registryMu.Unlock()
+
+	for locale, message := range messages {
+		RegisterMessages(locale, map[int64]string{code: message})
+	}
+
+	return nil
+}
+
+// MustRegister 同 Register, 失败时 panic, 一般在 init 中使用
+func MustRegister(code int64, messages map[string]string, httpStatus int) {
+	if err := Register(code, messages, httpStatus); err != nil {
+		panic(err)
+	}
+}
+
+// Status 错误码对应的 HTTP 状态码, 未注册或未指定时返回 200
+func Status(code int64) int {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	if entry, ok := registry[code]; ok && entry.status != 0 {
+		return entry.status
+	}
+
+	return http.StatusOK
+}
+
+// MappedStatus 错误码对应的真实 HTTP 状态码, 如 NeedLogin 为 401、NoAuth 为 403、RequestLimit 为 429
+// 用于开启 App.Response.HTTPStatus 或使用 RFC 7807 时, 注册时指定的状态码优先, 其他未知的错误码为 400
+func MappedStatus(code int64) int {
+	registryMu.RLock()
+	entry, ok := registry[code]
+	registryMu.RUnlock()
+	if !ok {
+		return http.StatusBadRequest
+	}
+
+	return mappedStatus(code, entry)
+}
+
+func mappedStatus(code int64, entry codeEntry) int {
+	switch {
+	case entry.status != 0:
+		return entry.status
+	case entry.mapped != 0:
+		return entry.mapped
+	case code == OK:
+		return http.StatusOK
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// Codes 所有错误码, 包括内置的、通过 Register 注册的和只在错误码提示文件中出现的, 按错误码排序
+func Codes() []CodeInfo {
+	registryMu.RLock()
+	infos := make(map[int64]*CodeInfo, len(registry))
+	for code, entry := range registry {
+		status := entry.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		infos[code] = &CodeInfo{Code: code, Name: entry.name, Status: status, Mapped: mappedStatus(code, entry), Builtin: entry.builtin}
+	}
+	registryMu.RUnlock()
+
+	catalogMu.RLock()
+	for locale, messages := range catalogs {
+		for code, message := range messages {
+			info, ok := infos[code]
+			if !ok {
+				info = &CodeInfo{Code: code, Status: http.StatusOK, Mapped: http.StatusBadRequest}
+				infos[code] = info
+			}
+			if info.Messages == nil {
+				info.Messages = make(map[string]string)
+			}
+			info.Messages[locale] = message
+		}
+	}
+	catalogMu.RUnlock()
+
+	codes := make([]CodeInfo, 0, len(infos))
+	for _, info := range infos {
+		codes = append(codes, *info)
+	}
+	slices.SortFunc(codes, func(a, b CodeInfo) int {
+		return cmp.Compare(a.Code, b.Code)
+	})
+
+	return codes
+}
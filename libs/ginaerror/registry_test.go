@@ -0,0 +1,125 @@
+package ginaerror
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// 注册测试用的错误码, 测试结束后删除, 不影响其他测试
+func register(t *testing.T, code int64, messages map[string]string, httpStatus int) error {
+	t.Helper()
+	err := Register(code, messages, httpStatus)
+	if err == nil {
+		t.Cleanup(func() {
+			registryMu.Lock()
+			delete(registry, code)
+			registryMu.Unlock()
+			catalogMu.Lock()
+			for _, catalog := range catalogs {
+				delete(catalog, code)
+			}
+			catalogMu.Unlock()
+		})
+	}
+
+	return err
+}
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name       string
+		code       int64
+		httpStatus int
+		existing   []int64 // 之前已经注册的错误码
+		wantErr    string
+	}{
+		{name: "注册成功", code: 90001, httpStatus: http.StatusConflict},
+		{name: "不指定状态码", code: 90001},
+		{name: "与内置错误码重复", code: NeedLogin, wantErr: "与内置错误码 NeedLogin 重复"},
+		{name: "与内置的 Fail 重复", code: Fail, wantErr: "与内置错误码 Fail 重复"},
+		{name: "重复注册", code: 90001, existing: []int64{90001}, wantErr: "错误码 90001 已注册"},
+		{name: "状态码小于 100", code: 90001, httpStatus: 99, wantErr: "HTTP 状态码 99 不合法"},
+		{name: "状态码大于 599", code: 90001, httpStatus: 600, wantErr: "HTTP 状态码 600 不合法"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, code := range tt.existing {
+				if err := register(t, code, nil, 0); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := register(t, tt.code, map[string]string{LocaleZh: "订单已关闭", LocaleEn: "Order closed"}, tt.httpStatus)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("错误为 %v, 应该包含 %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			wantStatus, wantMapped := tt.httpStatus, tt.httpStatus
+			if tt.httpStatus == 0 {
+				wantStatus, wantMapped = http.StatusOK, http.StatusBadRequest
+			}
+			if got := Status(tt.code); got != wantStatus {
+				t.Fatalf("状态码为 %d, 应该为 %d", got, wantStatus)
+			}
+			if got := MappedStatus(tt.code); got != wantMapped {
+				t.Fatalf("映射的状态码为 %d, 应该为 %d", got, wantMapped)
+			}
+			if message, _ := lookupMessage(LocaleEn, tt.code); message != "Order closed" {
+				t.Fatalf("提示为 %q", message)
+			}
+		})
+	}
+}
+
+// 重复注册失败时不覆盖已注册的状态码和提示
+func TestRegisterCollisionKeepsExisting(t *testing.T) {
+	if err := register(t, 90002, map[string]string{LocaleZh: "第一次"}, http.StatusConflict); err != nil {
+		t.Fatal(err)
+	}
+	if err := register(t, 90002, map[string]string{LocaleZh: "第二次"}, http.StatusGone); err == nil {
+		t.Fatal("重复注册应该返回错误")
+	}
+	if got := Status(90002); got != http.StatusConflict {
+		t.Fatalf("状态码为 %d, 应该保持为 %d", got, http.StatusConflict)
+	}
+	if message, _ := lookupMessage(LocaleZh, 90002); message != "第一次" {
+		t.Fatalf("提示为 %q, 应该保持为第一次注册的提示", message)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("MustRegister 重复注册时应该 panic")
+		}
+	}()
+	MustRegister(90002, nil, 0)
+}
+
+func TestMappedStatus(t *testing.T) {
+	tests := []struct {
+		code int64
+		want int
+	}{
+		{code: OK, want: http.StatusOK},
+		{code: Fail, want: http.StatusBadRequest},
+		{code: NeedLogin, want: http.StatusUnauthorized},
+		{code: NoAuth, want: http.StatusForbidden},
+		{code: RequestLimit, want: http.StatusTooManyRequests},
+		{code: ServerError, want: http.StatusInternalServerError},
+		{code: 99999, want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if got := MappedStatus(tt.code); got != tt.want {
+			t.Fatalf("MappedStatus(%d) = %d, 应该为 %d", tt.code, got, tt.want)
+		}
+		if got := Status(tt.code); got != http.StatusOK {
+			t.Fatalf("Status(%d) = %d, 内置错误码应该为 200", tt.code, got)
+		}
+	}
+}
@@ -1,49 +1,148 @@
 package ginaerror
 
 import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/locales/en"
 	"github.com/go-playground/locales/zh"
 	"github.com/go-playground/locales/zh_Hant_TW"
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
-	"strings"
 
 	en_translations "github.com/go-playground/validator/v10/translations/en"
 	zh_translations "github.com/go-playground/validator/v10/translations/zh"
+	zh_tw_translations "github.com/go-playground/validator/v10/translations/zh_tw"
 )
 
+// FieldError 单个字段的校验错误, 作为响应的 data 返回, 前端可以据此标记对应的表单项
+type FieldError struct {
+	Field   string `json:"field"`   // 字段在请求中的路径, 如 items[0].name
+	Rule    string `json:"rule"`    // 未通过的校验规则, 如 required
+	Message string `json:"message"` // 翻译后的提示
+}
+
+var (
+	// 每种语言的翻译器, 只在 Setup 时创建并注册到校验器, 之后只读, 可以并发使用
+	translators = make(map[string]ut.Translator)
+	setupOnce   sync.Once
+)
+
+// Setup 替换 gin 默认的校验器并注册各语言的翻译, 之后校验提示中的字段名称使用 label、json、form 中的名称,
+// 校验错误会记录字段在请求中的路径; gina 模块加载时会自动调用, 不使用 gina 时需要在绑定参数之前调用, 多次调用只会执行一次
+// 未调用时 Trans 等函数返回校验器原始的错误信息
+func Setup() {
+	setupOnce.Do(setup)
+}
+
+func setup() {
+	binding.Validator = &labelValidator{StructValidator: binding.Validator}
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(fieldLabel)
+
+	uni := ut.New(zh.New(), en.New(), zh_Hant_TW.New())
+	registers := map[string]struct {
+		name     string
+		register func(v *validator.Validate, trans ut.Translator) error
+	}{
+		LocaleZh:     {"zh", zh_translations.RegisterDefaultTranslations},
+		LocaleEn:     {"en", en_translations.RegisterDefaultTranslations},
+		LocaleZhHant: {"zh_Hant_TW", zh_tw_translations.RegisterDefaultTranslations},
+	}
+	for locale, item := range registers {
+		trans, _ := uni.GetTranslator(item.name)
+		if err := item.register(v, trans); err != nil {
+			panic(err)
+		}
+		translators[locale] = trans
+	}
+}
+
+// Trans 以默认语言翻译参数校验错误, 不是校验错误时返回 err.Error()
 func Trans(err error) string {
-	locale := "zh"
-	var ret []string
-	if validationErrors, ok := err.(validator.ValidationErrors); !ok {
+	return TransLocale(DefaultLocale(), err)
+}
+
+// TransCtx 以当前请求的语言翻译参数校验错误, 语言的解析见 LocaleFromContext
+func TransCtx(ctx context.Context, err error) string {
+	return TransLocale(LocaleFromContext(ctx), err)
+}
+
+// TransLocale 以指定语言翻译参数校验错误, 多个错误以 ; 分隔
+func TransLocale(locale string, err error) string {
+	fields := transFields(locale, err)
+	if fields == nil {
 		return err.Error()
-	} else {
-		for _, e := range validationErrors {
-			ret = append(ret, e.Translate(getTranslator(locale)))
-		}
+	}
+
+	ret := make([]string, 0, len(fields))
+	for _, field := range fields {
+		ret = append(ret, field.Message)
 	}
 
 	return strings.Join(ret, ";")
 }
 
-func getTranslator(locale string) ut.Translator {
-	uni := ut.New(en.New(), zh.New(), zh_Hant_TW.New())
-	trans, _ := uni.GetTranslator(locale)
-	v, ok := binding.Validator.Engine().(*validator.Validate)
-	if ok {
-		switch locale {
-		case "zh":
-			_ = zh_translations.RegisterDefaultTranslations(v, trans)
-			break
-		case "en":
-			_ = en_translations.RegisterDefaultTranslations(v, trans)
-			break
-		default:
-			_ = zh_translations.RegisterDefaultTranslations(v, trans)
-			break
+// TransFields 以当前请求的语言翻译参数校验错误, 返回每个字段的错误, 不是校验错误时返回 nil
+func TransFields(ctx context.Context, err error) []FieldError {
+	return transFields(LocaleFromContext(ctx), err)
+}
+
+// TransError 把绑定参数的错误转为业务错误, 校验错误的提示按当前请求的语言翻译, 每个字段的错误作为 Details 返回
+// 其他错误如 JSON 格式错误, 以 err.Error() 作为提示, 与 gina.FailWithMessage(ctx, ginaerror.Trans(err)) 一致
+func TransError(ctx context.Context, err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	fields := TransFields(ctx, err)
+	if fields == nil {
+		return New(Fail, err.Error())
+	}
+	messages := make([]string, 0, len(fields))
+	for _, field := range fields {
+		messages = append(messages, field.Message)
+	}
+
+	return New(Fail, strings.Join(messages, ";")).WithDetails(fields)
+}
+
+func transFields(locale string, err error) []FieldError {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return nil
+	}
+
+	trans := Translator(locale)
+	fields := make([]FieldError, 0, len(validationErrors))
+	for _, e := range validationErrors {
+		field := FieldError{Rule: e.Tag(), Message: e.Translate(trans)}
+		if labeled, ok := e.(*labeledFieldError); ok {
+			field.Field = labeled.path
+		} else {
+			_, field.Field, _ = strings.Cut(e.Namespace(), ".")
 		}
+		fields = append(fields, field)
+	}
+
+	return fields
+}
+
+// Translator 指定语言的翻译器, 可以用于注册自定义校验规则的翻译
+// 校验器只支持 zh、en 和 zh_Hant, 其他语言使用默认语言, 默认语言也不支持时使用中文
+func Translator(locale string) ut.Translator {
+	if trans, ok := translators[locale]; ok {
+		return trans
+	}
+	if trans, ok := translators[DefaultLocale()]; ok {
+		return trans
 	}
 
-	return trans
+	return translators[LocaleZh]
 }
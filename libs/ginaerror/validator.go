@@ -0,0 +1,121 @@
+package ginaerror
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// LabelTag 字段在校验提示中的名称, 如 `json:"username" label:"用户名"`, 未设置时使用 json 或 form 中的名称
+const LabelTag = "label"
+
+// 替换 gin 默认的校验器, 校验失败时记录字段在请求中的路径, 用于返回结构化的字段错误
+type labelValidator struct {
+	binding.StructValidator
+}
+
+func (self *labelValidator) ValidateStruct(obj any) error {
+	err := self.StructValidator.ValidateStruct(obj)
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+
+	typ := reflect.TypeOf(obj)
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return err
+	}
+	for i, e := range validationErrors {
+		validationErrors[i] = &labeledFieldError{FieldError: e, path: fieldPath(typ, e.StructNamespace())}
+	}
+
+	return validationErrors
+}
+
+// 与原来的错误一致, 只是多了字段在请求中的路径, 不影响 validator.ValidationErrors 的类型断言
+type labeledFieldError struct {
+	validator.FieldError
+	path string
+}
+
+// 校验提示中的字段名称, 依次使用 label、json、form 中的名称, 都没有时使用字段名
+// 不能返回 -, 否则 validator 会跳过这个字段的校验
+func fieldLabel(field reflect.StructField) string {
+	if label := field.Tag.Get(LabelTag); label != "" && label != "-" {
+		return label
+	}
+
+	return fieldName(field)
+}
+
+// 字段在请求中的名称, 依次使用 json、form 中的名称, 都没有时使用字段名
+func fieldName(field reflect.StructField) string {
+	for _, key := range []string{"json", "form"} {
+		name, _, _ := strings.Cut(field.Tag.Get(key), ",")
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+
+	return field.Name
+}
+
+// 把 StructNamespace 转为请求中的路径, 如 CreateReq.Items[0].Name 转为 items[0].name, 匿名嵌入的结构体不出现在路径中
+// 匿名的结构体类型没有名称, StructNamespace 直接以字段开头, 如 Items[0].Name
+func fieldPath(typ reflect.Type, structNamespace string) string {
+	segments := strings.Split(structNamespace, ".")
+	if typ.Name() != "" {
+		segments = segments[1:]
+	}
+	path := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		name, index, hasIndex := strings.Cut(segment, "[")
+		for typ != nil && typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		if typ == nil || typ.Kind() != reflect.Struct {
+			typ = nil
+			path = append(path, segment)
+			continue
+		}
+
+		field, ok := typ.FieldByName(name)
+		if !ok {
+			typ = nil
+			path = append(path, segment)
+			continue
+		}
+		typ = field.Type
+		if field.Anonymous && !hasIndex && field.Tag.Get("json") == "" {
+			continue
+		}
+		if !hasIndex {
+			path = append(path, fieldName(field))
+			continue
+		}
+
+		// 每一层下标对应一层元素类型, 如 [0][1]
+		for range strings.Count(index, "[") + 1 {
+			for typ.Kind() == reflect.Pointer {
+				typ = typ.Elem()
+			}
+			switch typ.Kind() {
+			case reflect.Slice, reflect.Array, reflect.Map:
+				typ = typ.Elem()
+			default:
+				typ = nil
+			}
+			if typ == nil {
+				break
+			}
+		}
+		path = append(path, fieldName(field)+"["+index)
+	}
+
+	return strings.Join(path, ".")
+}
@@ -0,0 +1,112 @@
+package ginaerror
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+type Address struct {
+	City string `json:"city" binding:"required"`
+}
+
+type Base struct {
+	Tenant string `form:"tenant"`
+}
+
+type Item struct {
+	Sku  string `json:"sku" label:"商品编号" binding:"required"`
+	Tags []string
+}
+
+type createReq struct {
+	Base
+	Name    string            `json:"name,omitempty" label:"名称"`
+	Ignored string            `json:"-"`
+	Items   []*Item           `json:"items"`
+	Matrix  [][]Item          `json:"matrix"`
+	Extra   map[string]Item   `json:"extra"`
+	Home    *Address          `json:"home"`
+	Named   Address           `json:"named_address"`
+	Labels  map[string]string `form:"labels"`
+}
+
+func TestFieldPath(t *testing.T) {
+	typ := reflect.TypeOf(createReq{})
+	tests := []struct {
+		namespace string
+		want      string
+	}{
+		{namespace: "createReq.Name", want: "name"},
+		{namespace: "createReq.Ignored", want: "Ignored"},
+		{namespace: "createReq.Base.Tenant", want: "tenant"},
+		{namespace: "createReq.Items[0].Sku", want: "items[0].sku"},
+		{namespace: "createReq.Items[2].Tags[1]", want: "items[2].Tags[1]"},
+		{namespace: "createReq.Matrix[0][1].Sku", want: "matrix[0][1].sku"},
+		{namespace: "createReq.Extra[a].Sku", want: "extra[a].sku"},
+		{namespace: "createReq.Home.City", want: "home.city"},
+		{namespace: "createReq.Named.City", want: "named_address.city"},
+		{namespace: "createReq.Labels[k]", want: "labels[k]"},
+		{namespace: "createReq.Missing.Name", want: "Missing.Name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.namespace, func(t *testing.T) {
+			if got := fieldPath(typ, tt.namespace); got != tt.want {
+				t.Fatalf("路径为 %s, 应该为 %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// 匿名的结构体类型, StructNamespace 中没有类型名称
+func TestFieldPathAnonymous(t *testing.T) {
+	typ := reflect.TypeOf(struct {
+		Items []Item `json:"items"`
+	}{})
+	tests := []struct {
+		namespace string
+		want      string
+	}{
+		{namespace: "Items[1].Sku", want: "items[1].sku"},
+		{namespace: "Items", want: "items"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.namespace, func(t *testing.T) {
+			if got := fieldPath(typ, tt.namespace); got != tt.want {
+				t.Fatalf("路径为 %s, 应该为 %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// Setup 之后校验错误使用 label 作为字段名称, 并记录字段在请求中的路径
+func TestSetup(t *testing.T) {
+	// 包中的其他测试都不调用 Setup, 只导入时不应该替换校验器
+	if _, ok := binding.Validator.(*labelValidator); ok {
+		t.Fatal("导入 ginaerror 时不应该替换校验器")
+	}
+
+	Setup()
+	Setup()
+	if _, ok := binding.Validator.(*labelValidator); !ok {
+		t.Fatalf("校验器为 %T, 应该被替换", binding.Validator)
+	}
+
+	req := struct {
+		Items []Item `json:"items" binding:"dive"`
+	}{Items: []Item{{Sku: "a"}, {}}}
+	err := binding.Validator.ValidateStruct(&req)
+	fields := transFields(LocaleZh, err)
+	if len(fields) != 1 {
+		t.Fatalf("字段错误为 %v", fields)
+	}
+	want := FieldError{Field: "items[1].sku", Rule: "required", Message: "商品编号为必填字段"}
+	if fields[0] != want {
+		t.Fatalf("字段错误为 %+v, 应该为 %+v", fields[0], want)
+	}
+	if got := TransError(context.Background(), err); got.Code != Fail {
+		t.Fatalf("错误码为 %d, 应该为 %d", got.Code, Fail)
+	}
+}
@@ -0,0 +1,239 @@
+// Package ginagrace 通过传递监听的 socket 实现不中断服务的重启
+//
+// 收到 SIGHUP 后, 当前进程以相同的参数启动新的进程, 并通过文件描述符把所有监听传递给它,
+// 新的进程所有服务都启动完成后通过 Ready 通知当前进程, 当前进程再停止接收新的连接, 等待处理中的请求完成后退出
+package ginagrace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/soryetong/greasyx/console"
+)
+
+const (
+	// 传递给新进程的监听, 按顺序对应从 3 开始的文件描述符, 如 tcp|:8080,unix|/tmp/app.sock
+	// 不能使用 GREASYX_ 前缀, 否则会被当作覆盖配置的环境变量
+	envListenFds = "GINAGRACE_LISTEN_FDS"
+	// 新进程就绪后向该文件描述符写入数据通知当前进程
+	envReadyFd = "GINAGRACE_READY_FD"
+
+	firstFd = 3
+)
+
+var (
+	mu         sync.Mutex
+	inherited  map[string]net.Listener // 从父进程继承, 还未被使用的监听, Ready 之后为空
+	active     []*listener             // 当前进程正在使用的监听, 重启时传递给新进程
+	readyFile  *os.File
+	parsed     bool
+	readyOnce  sync.Once
+	isChildEnv = os.Getenv(envListenFds) != "" || os.Getenv(envReadyFd) != ""
+)
+
+// IsChild 当前进程是否是通过重启启动的
+func IsChild() bool {
+	return isChildEnv
+}
+
+// Listen 监听地址, 优先使用从父进程继承的监听, unix socket 新建监听时会先删除已存在的 socket 文件
+func Listen(network, addr string) (net.Listener, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	parseInherited()
+
+	key := network + "|" + addr
+	ln, ok := inherited[key]
+	if ok {
+		delete(inherited, key)
+	} else {
+		if network == "unix" {
+			if err := os.Remove(addr); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("删除旧的 socket 文件 %s 失败: %w", addr, err)
+			}
+		}
+		var err error
+		if ln, err = net.Listen(network, addr); err != nil {
+			return nil, err
+		}
+	}
+
+	wrapped := &listener{Listener: ln, key: key}
+	active = append(active, wrapped)
+
+	return wrapped, nil
+}
+
+// Ready 通知父进程新的进程已就绪, 由服务管理器在所有服务启动完成后调用, 只会通知一次, 非重启启动时不做任何事情
+// 父进程传递但没有被使用的监听会被关闭, 如新版本去掉了某个监听地址, 之后再监听相同的地址时会新建监听
+func Ready() {
+	mu.Lock()
+	defer mu.Unlock()
+	parseInherited()
+
+	readyOnce.Do(func() {
+		for key, ln := range inherited {
+			_ = ln.Close()
+			console.Echo.Warnf("⚠️ 警告: 从旧进程继承的监听 %s 没有被使用, 已关闭\n", key)
+		}
+		inherited = make(map[string]net.Listener)
+
+		if readyFile != nil {
+			_, _ = readyFile.Write([]byte{1})
+			_ = readyFile.Close()
+			readyFile = nil
+		}
+	})
+}
+
+// Restart 以相同的参数启动新的进程并传递所有监听, 新进程就绪后返回
+// ctx 到期或者新进程异常退出时, 结束新进程并返回错误, 当前进程继续提供服务
+func Restart(ctx context.Context) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	keys := make([]string, 0, len(active))
+	files := make([]*os.File, 0, len(active))
+	for _, ln := range active {
+		filer, ok := ln.Listener.(interface{ File() (*os.File, error) })
+		if !ok {
+			continue
+		}
+		file, err := filer.File()
+		if err != nil {
+			mu.Unlock()
+			closeFiles(files)
+			return fmt.Errorf("获取监听 %s 的文件描述符失败: %w", ln.key, err)
+		}
+		keys = append(keys, ln.key)
+		files = append(files, file)
+	}
+	mu.Unlock()
+	defer closeFiles(files)
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer readyR.Close()
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = append(files, readyW)
+	cmd.Env = append(filterEnv(os.Environ()),
+		envListenFds+"="+strings.Join(keys, ","),
+		envReadyFd+"="+strconv.Itoa(firstFd+len(files)),
+	)
+	err = cmd.Start()
+	_ = readyW.Close()
+	if err != nil {
+		return fmt.Errorf("启动新进程失败: %w", err)
+	}
+
+	ready := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		if _, err := readyR.Read(buf); err != nil {
+			ready <- fmt.Errorf("新进程未就绪就退出了: %w", err)
+			return
+		}
+		ready <- nil
+	}()
+	go func() {
+		_ = cmd.Wait()
+	}()
+
+	select {
+	case err = <-ready:
+	case <-ctx.Done():
+		err = fmt.Errorf("等待新进程就绪超时: %w", ctx.Err())
+	}
+	if err != nil {
+		_ = cmd.Process.Kill()
+		return err
+	}
+
+	// unix socket 的文件已经由新进程使用, 当前进程关闭监听时不能删除
+	mu.Lock()
+	for _, ln := range active {
+		if unixLn, ok := ln.Listener.(*net.UnixListener); ok {
+			unixLn.SetUnlinkOnClose(false)
+		}
+	}
+	mu.Unlock()
+
+	return nil
+}
+
+// 解析从父进程继承的监听, 只解析一次
+func parseInherited() {
+	if parsed {
+		return
+	}
+	parsed = true
+	inherited = make(map[string]net.Listener)
+
+	if fd, err := strconv.Atoi(os.Getenv(envReadyFd)); err == nil {
+		readyFile = os.NewFile(uintptr(fd), "ready")
+	}
+	if value := os.Getenv(envListenFds); value != "" {
+		for i, key := range strings.Split(value, ",") {
+			file := os.NewFile(uintptr(firstFd+i), key)
+			ln, err := net.FileListener(file)
+			_ = file.Close()
+			if err != nil {
+				continue
+			}
+			inherited[key] = ln
+		}
+	}
+	_ = os.Unsetenv(envListenFds)
+	_ = os.Unsetenv(envReadyFd)
+}
+
+func filterEnv(environ []string) []string {
+	env := make([]string, 0, len(environ))
+	for _, kv := range environ {
+		if strings.HasPrefix(kv, envListenFds+"=") || strings.HasPrefix(kv, envReadyFd+"=") {
+			continue
+		}
+		env = append(env, kv)
+	}
+
+	return env
+}
+
+func closeFiles(files []*os.File) {
+	for _, file := range files {
+		_ = file.Close()
+	}
+}
+
+// 关闭后不再传递给新进程
+type listener struct {
+	net.Listener
+	key string
+}
+
+func (self *listener) Close() error {
+	mu.Lock()
+	for i, ln := range active {
+		if ln == self {
+			active = append(active[:i], active[i+1:]...)
+			break
+		}
+	}
+	mu.Unlock()
+
+	return self.Listener.Close()
+}
@@ -0,0 +1,147 @@
+package ginagrace
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 重启时 Restart 以相同的参数启动测试程序自身, 通过该环境变量决定新进程的行为
+const envHelper = "GINAGRACE_TEST_HELPER"
+
+const helperAddr = "127.0.0.1:0"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(envHelper); mode != "" && IsChild() {
+		runHelper(mode)
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+func runHelper(mode string) {
+	switch mode {
+	case "serve":
+		// 使用继承的监听, 就绪后处理一个连接
+		ln, err := Listen("tcp", helperAddr)
+		if err != nil {
+			os.Exit(1)
+		}
+		Ready()
+		conn, err := ln.Accept()
+		if err != nil {
+			os.Exit(1)
+		}
+		_, _ = conn.Write([]byte("child"))
+		_ = conn.Close()
+	case "unused":
+		// 不再使用继承的监听, 就绪后保持运行一段时间
+		Ready()
+		time.Sleep(2 * time.Second)
+	case "exit":
+		os.Exit(1)
+	case "hang":
+		time.Sleep(time.Minute)
+	}
+}
+
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+	ln, err := Listen("tcp", helperAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	return ln
+}
+
+func TestRestart(t *testing.T) {
+	tests := []struct {
+		mode    string
+		timeout time.Duration
+		wantErr string
+		check   func(t *testing.T, addr string)
+	}{
+		{
+			mode:    "serve",
+			timeout: 10 * time.Second,
+			check: func(t *testing.T, addr string) {
+				conn, err := net.DialTimeout("tcp", addr, time.Second)
+				if err != nil {
+					t.Fatalf("旧进程关闭监听后应该由新进程处理连接: %s", err)
+				}
+				defer conn.Close()
+				_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+				data, _ := io.ReadAll(conn)
+				if string(data) != "child" {
+					t.Fatalf("收到 %q, 应该由新进程处理", data)
+				}
+			},
+		},
+		{
+			mode:    "unused",
+			timeout: 10 * time.Second,
+			check: func(t *testing.T, addr string) {
+				// 新进程仍在运行, 但已经关闭了没有使用的监听
+				if conn, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+					_ = conn.Close()
+					t.Fatal("没有被使用的监听应该在新进程就绪时关闭")
+				}
+			},
+		},
+		{mode: "exit", timeout: 10 * time.Second, wantErr: "新进程未就绪就退出了"},
+		{mode: "hang", timeout: 500 * time.Millisecond, wantErr: "等待新进程就绪超时"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			t.Setenv(envHelper, tt.mode)
+			ln := listen(t)
+			addr := ln.Addr().String()
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			err := Restart(ctx)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("错误为 %v, 应该包含 %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// 与服务管理器一样, 新进程就绪后旧进程停止监听
+			_ = ln.Close()
+			tt.check(t, addr)
+		})
+	}
+}
+
+// 关闭后的监听不再传递给新进程
+func TestListenerClose(t *testing.T) {
+	ln := listen(t)
+	_ = ln.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, item := range active {
+		if item == ln {
+			t.Fatal("关闭后的监听仍在传递的列表中")
+		}
+	}
+}
+
+func TestFilterEnv(t *testing.T) {
+	environ := []string{"PATH=/bin", envListenFds + "=tcp|:8080", envReadyFd + "=4", "GREASYX_APP_ENV=prod"}
+	got := filterEnv(environ)
+	if len(got) != 2 || got[0] != "PATH=/bin" || got[1] != "GREASYX_APP_ENV=prod" {
+		t.Fatalf("过滤后为 %v", got)
+	}
+}
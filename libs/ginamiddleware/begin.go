@@ -1,14 +1,49 @@
 package ginamiddleware
 
 import (
+	"net/http"
+
 	"github.com/gin-gonic/gin"
-	uuid "github.com/satori/go.uuid"
+	"github.com/soryetong/greasyx/libs/ginatrace"
 )
 
+// Begin 为每个请求创建 span, 上游通过 traceparent 传递了链路信息时沿用上游的 trace_id
+// trace_id 同时保存在 gin.Context 中, 用于日志关联
 func Begin() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		ctx.Set("trace_id", uuid.NewV4().String())
+		route := ctx.FullPath()
+		if route == "" {
+			route = ctx.Request.URL.Path
+		}
+
+		opts := []ginatrace.StartOption{
+			ginatrace.WithKind(ginatrace.SpanKindServer),
+			ginatrace.WithAttributes(map[string]any{
+				"http.request.method": ctx.Request.Method,
+				"http.route":          route,
+				"url.path":            ctx.Request.URL.Path,
+				"client.address":      ctx.ClientIP(),
+			}),
+		}
+		if remote, ok := ginatrace.Extract(ctx.Request.Header); ok {
+			opts = append(opts, ginatrace.WithRemoteParent(remote))
+		}
+		spanCtx, span := ginatrace.Start(ctx.Request.Context(), ctx.Request.Method+" "+route, opts...)
+		defer span.End()
+
+		ctx.Request = ctx.Request.WithContext(spanCtx)
+		ctx.Set(ginatrace.GinSpanKey, span)
+		ctx.Set("trace_id", span.TraceID())
 		ctx.Set("source", "HttpRequest")
 		ctx.Next()
+
+		status := ctx.Writer.Status()
+		span.SetAttr("http.response.status_code", status)
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(ginatrace.StatusError, http.StatusText(status))
+		}
+		if err := ctx.Errors.Last(); err != nil {
+			span.RecordError(err.Err)
+		}
 	}
 }
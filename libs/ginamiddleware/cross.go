@@ -1,16 +1,51 @@
 package ginamiddleware
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
+	"sync/atomic"
 
 	"github.com/gin-gonic/gin"
+	"github.com/soryetong/greasyx/console"
+	"github.com/soryetong/greasyx/gina"
+	"github.com/spf13/viper"
 )
 
+type corsConfig struct {
+	AllowOrigins []string
+}
+
+// 允许跨域的来源, 支持通过配置热更新
+var allowOrigins atomic.Pointer[[]string]
+
+func init() {
+	gina.OnConfigChange("Cors", func(old, new corsConfig) {
+		origins := originsOrDefault(new.AllowOrigins)
+		allowOrigins.Store(&origins)
+		console.Echo.Infof("✅ 提示: 跨域来源已更新为 %v\n", origins)
+	}, func(new corsConfig) error {
+		return validateOrigins(new.AllowOrigins)
+	})
+}
+
+// Cross 跨域中间件, 通过 Cors.AllowOrigins 配置允许的来源, 默认允许所有来源
 func Cross() gin.HandlerFunc {
+	if allowOrigins.Load() == nil {
+		origins := originsOrDefault(viper.GetStringSlice("Cors.AllowOrigins"))
+		allowOrigins.Store(&origins)
+	}
+
 	return func(ctx *gin.Context) {
 		method := ctx.Request.Method
-		ctx.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		ctx.Header("Access-Control-Allow-Origin", "*")
+		origins := *allowOrigins.Load()
+		if slices.Contains(origins, "*") {
+			ctx.Header("Access-Control-Allow-Origin", "*")
+		} else if origin := ctx.GetHeader("Origin"); slices.Contains(origins, origin) {
+			ctx.Header("Access-Control-Allow-Origin", origin)
+			ctx.Header("Vary", "Origin")
+		}
 		ctx.Header("Access-Control-Allow-Headers", "Authorization,Channel, Uid, Content-Length, X-CSRF-Token,"+
 			" Token,session,X_Requested_With,Accept, Origin, Host, Connection, Accept-Encoding, Accept-Language,DNT, "+
 			"X-CustomHeader, Keep-Alive, User-Agent, X-Requested-With, If-Modified-Since, Cache-Control, Content-Type, "+
@@ -30,3 +65,25 @@ func Cross() gin.HandlerFunc {
 		ctx.Next()
 	}
 }
+
+func originsOrDefault(origins []string) []string {
+	if len(origins) == 0 {
+		return []string{"*"}
+	}
+
+	return origins
+}
+
+func validateOrigins(origins []string) error {
+	for _, origin := range origins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+			return fmt.Errorf("无效的跨域来源: %s", origin)
+		}
+	}
+
+	return nil
+}
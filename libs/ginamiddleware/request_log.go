@@ -78,8 +78,12 @@ func RequestLog() gin.HandlerFunc {
 
 		elapsedMs := time.Since(startTime).Seconds() * 1000
 		logData.Elapsed = fmt.Sprintf("%.2f", elapsedMs)
-		resp := &gina.Response{}
-		_ = json.Unmarshal([]byte(writer.body.String()), resp)
+		// 优先使用 gina 保存的响应, 自定义响应格式时也能读取
+		resp, ok := ctx.Value("response").(*gina.Response)
+		if !ok {
+			resp = &gina.Response{}
+			_ = json.Unmarshal([]byte(writer.body.String()), resp)
+		}
 		logData.StatusCode = resp.Code
 		logData.Msg = resp.Msg
 		respData, _ := json.Marshal(resp.Data)
@@ -2,6 +2,7 @@ package ginasrv
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"io"
@@ -12,6 +13,7 @@ import (
 	"time"
 
 	"github.com/soryetong/greasyx/ginahelper"
+	"github.com/soryetong/greasyx/libs/ginatrace"
 )
 
 const (
@@ -23,6 +25,7 @@ const (
 
 // RequestConfig 封装请求参数
 type RequestConfig struct {
+	Ctx       context.Context // 为空时开始一条新的链路, 通过 traceparent 请求头把链路信息传递给下游服务
 	Method    string
 	Url       string
 	Headers   map[string]string
@@ -63,14 +66,27 @@ func DoRequest(cfg RequestConfig) ([]byte, int, error) {
 		}
 	}
 
-	req, err := http.NewRequest(cfg.Method, parsedUrl.String(), bodyReader)
+	ctx := cfg.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, span := ginatrace.Start(ctx, method, ginatrace.WithKind(ginatrace.SpanKindClient), ginatrace.WithAttributes(map[string]any{
+		"http.request.method": method,
+		"url.full":            parsedUrl.Scheme + "://" + parsedUrl.Host + parsedUrl.Path, // 参数中可能有敏感信息, 不记录
+		"server.address":      parsedUrl.Host,
+	}))
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, cfg.Method, parsedUrl.String(), bodyReader)
 	if err != nil {
+		span.RecordError(err)
 		return nil, 0, err
 	}
 
 	for k, v := range cfg.Headers {
 		req.Header.Set(k, v)
 	}
+	ginatrace.Inject(span, req.Header)
 
 	transport := &http.Transport{
 		TLSClientConfig: cfg.TLSConfig,
@@ -95,9 +111,14 @@ func DoRequest(cfg RequestConfig) ([]byte, int, error) {
 
 	resp, err := client.Do(req)
 	if err != nil {
+		span.RecordError(err)
 		return nil, 0, err
 	}
 	defer resp.Body.Close()
+	span.SetAttr("http.response.status_code", resp.StatusCode)
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(ginatrace.StatusError, resp.Status)
+	}
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
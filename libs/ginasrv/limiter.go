@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -75,10 +76,81 @@ func NewLimiterStoreFromFile(path string) *LimiterStore {
 	return NewLimiterStore(cfg.Rules, cfg.Mode, cfg.Ttl)
 }
 
+// 从配置文件的 Limiter 节点加载的限流器, 多次加载时共用同一个, 配置变化时更新
+var (
+	confStoreMu   sync.Mutex
+	confStore     *LimiterStore
+	confWatchOnce sync.Once
+)
+
+// 从配置文件的 Limiter 节点加载限流规则, 配置变化时自动更新规则, 多次调用返回同一个限流器
+func NewLimiterStoreFromConf() *LimiterStore {
+	store, err := LoadLimiterStoreFromConf()
+	if err != nil {
+		console.Echo.Fatalf("❌ 错误: %s", err)
+	}
+
+	return store
+}
+
+// LoadLimiterStoreFromConf 同 NewLimiterStoreFromConf, 规则不合法时返回错误而不是退出程序
+func LoadLimiterStoreFromConf() (*LimiterStore, error) {
+	confStoreMu.Lock()
+	defer confStoreMu.Unlock()
+	if confStore != nil {
+		return confStore, nil
+	}
+
+	var cfg config
+	if err := viper.UnmarshalKey("Limiter", &cfg); err != nil {
+		return nil, fmt.Errorf("读取限流规则错误: %w", err)
+	}
+	if err := validateLimiterConfig(&cfg); err != nil {
+		return nil, fmt.Errorf("限流规则错误: %w", err)
+	}
+
+	store := NewLimiterStore(cfg.Rules, cfg.Mode, cfg.Ttl)
+	confStore = store
+	confWatchOnce.Do(func() {
+		gina.OnConfigChange("Limiter", func(old, new config) {
+			store.update(new)
+			console.Echo.Infof("✅ 提示: 限流规则热更新成功")
+		}, func(new config) error {
+			return validateLimiterConfig(&new)
+		})
+	})
+
+	return confStore, nil
+}
+
+// 校验限流规则, 规则不合法时返回第一个错误
+func validateLimiterConfig(cfg *config) error {
+	if cfg.Mode != LimitRuleModeUri && cfg.Mode != LimitRuleModeComm {
+		return fmt.Errorf("不支持的限流模式: %s", cfg.Mode)
+	}
+	if cfg.Ttl < 0 {
+		return fmt.Errorf("ttl 不能小于 0")
+	}
+
+	for i, rule := range cfg.Rules {
+		if rule.Rate <= 0 || rule.Burst <= 0 {
+			return fmt.Errorf("第 %d 条规则的 Rate 和 Burst 必须大于 0", i+1)
+		}
+		if cfg.Mode == LimitRuleModeUri && rule.Route == "" {
+			return fmt.Errorf("第 %d 条规则未配置 Route", i+1)
+		}
+		for _, keyType := range strings.Split(rule.KeyType, "+") {
+			if keyType != LimitRuleKeyTypeIp && keyType != LimitRuleKeyTypeUserid && keyType != LimitRuleKeyTypeUrl {
+				return fmt.Errorf("第 %d 条规则的 KeyType 不合法: %s", i+1, rule.KeyType)
+			}
+		}
+	}
+
+	return nil
+}
+
 // 生成组合 key
-func buildKey(ctx *gin.Context, keyType, uri string) string {
-	ip := ctx.ClientIP()
-	userId := ginaauth.GetTokenData[int64](ctx, "id")
+func buildKey(ip string, userId int64, keyType, uri string) string {
 	parts := strings.Split(keyType, "+")
 	var vals []string
 	for _, p := range parts {
@@ -115,32 +187,61 @@ func (self *LimiterStore) getLimiter(key string, rule LimitRule) *rate.Limiter {
 
 // 限流判断
 func (self *LimiterStore) Allow(ctx *gin.Context) bool {
+	uri := ginahelper.ConvertToRestfulURL(strings.TrimPrefix(ctx.Request.URL.Path, viper.GetString("App.RouterPrefix")))
+
+	return self.AllowRequest(ctx.ClientIP(), ginaauth.GetTokenData[int64](ctx, "id"), uri)
+}
+
+// AllowRequest 不依赖 gin.Context 的限流判断, uri 与规则中的 Route 比较, 如 gRPC 的 /pkg.Service/Method
+func (self *LimiterStore) AllowRequest(ip string, userId int64, uri string) bool {
 	self.mu.RLock()
 	defer self.mu.RUnlock()
 
 	if len(self.rules) == 0 {
 		return true
 	}
-	uri := ginahelper.ConvertToRestfulURL(strings.TrimPrefix(ctx.Request.URL.Path, viper.GetString("App.RouterPrefix")))
 	if self.mode == LimitRuleModeComm {
 		rule := self.rules[0]
-		key := buildKey(ctx, rule.KeyType, uri)
+		key := buildKey(ip, userId, rule.KeyType, uri)
 		limiter := self.getLimiter(key, rule)
-		return limiter.Allow()
+		return countRejected(LimitRuleModeComm, limiter.Allow())
 	}
 
 	// 按接口配置限流
 	for _, rule := range self.rules {
 		if "/"+strings.Trim(rule.Route, "/") == uri {
-			key := uri + "|" + buildKey(ctx, rule.KeyType, uri)
+			key := uri + "|" + buildKey(ip, userId, rule.KeyType, uri)
 			limiter := self.getLimiter(key, rule)
-			return limiter.Allow()
+			return countRejected(rule.Route, limiter.Allow())
 		}
 	}
 
 	return true
 }
 
+// 按规则统计被限流的请求数, 通用限流的规则为 comm
+var limiterRejected sync.Map // route(string) => *atomic.Uint64
+
+func countRejected(route string, allowed bool) bool {
+	if !allowed {
+		counter, _ := limiterRejected.LoadOrStore(route, new(atomic.Uint64))
+		counter.(*atomic.Uint64).Add(1)
+	}
+
+	return allowed
+}
+
+// LimiterRejected 获取各规则累计被限流的请求数, 键为规则的 Route, 通用限流为 comm
+func LimiterRejected() map[string]uint64 {
+	result := make(map[string]uint64)
+	limiterRejected.Range(func(key, value any) bool {
+		result[key.(string)] = value.(*atomic.Uint64).Load()
+		return true
+	})
+
+	return result
+}
+
 // 支持后期更新规则
 func (self *LimiterStore) UpdateRules(newRules []LimitRule, mode string) {
 	self.mu.Lock()
@@ -150,6 +251,16 @@ func (self *LimiterStore) UpdateRules(newRules []LimitRule, mode string) {
 	self.mode = mode
 }
 
+// 按配置更新规则和限流器的过期时间, 过期时间只对之后创建的限流器生效
+func (self *LimiterStore) update(cfg config) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	self.rules = cfg.Rules
+	self.mode = cfg.Mode
+	self.ttl = cfg.Ttl
+}
+
 func LoadLimiterRulesFromFile(path string) (*config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -0,0 +1,160 @@
+// Package ginatrace 基于 W3C Trace Context 的链路追踪
+//
+// 通过 traceparent 和 tracestate 请求头在服务之间传递链路信息, 在 HTTP 处理、数据库、Redis 和对外请求中记录 span,
+// 设置 Exporter 后批量导出到 OTLP/HTTP 的收集器或者标准输出, 未设置时只传递链路信息, 不导出 span
+package ginatrace
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+)
+
+// W3C Trace Context 的请求头
+const (
+	HeaderTraceparent = "traceparent"
+	HeaderTracestate  = "tracestate"
+)
+
+// tracestate 最多 32 个键值对
+const maxTraceStateMembers = 32
+
+// TraceID 链路 ID, 同一条链路中的所有 span 相同
+type TraceID [16]byte
+
+// SpanID span ID
+type SpanID [8]byte
+
+func (self TraceID) String() string {
+	return hex.EncodeToString(self[:])
+}
+
+func (self TraceID) IsValid() bool {
+	return self != TraceID{}
+}
+
+func (self SpanID) String() string {
+	return hex.EncodeToString(self[:])
+}
+
+func (self SpanID) IsValid() bool {
+	return self != SpanID{}
+}
+
+func newTraceID() (id TraceID) {
+	for !id.IsValid() {
+		for i := 0; i < len(id); i += 8 {
+			binary.BigEndian.PutUint64(id[i:], rand.Uint64())
+		}
+	}
+
+	return
+}
+
+func newSpanID() (id SpanID) {
+	for !id.IsValid() {
+		binary.BigEndian.PutUint64(id[:], rand.Uint64())
+	}
+
+	return
+}
+
+// SpanContext 在服务之间传递的链路信息
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool   // 是否采样, 未采样的 span 不会导出
+	TraceState string // 其他追踪系统的信息, 原样传递
+}
+
+func (self SpanContext) IsValid() bool {
+	return self.TraceID.IsValid() && self.SpanID.IsValid()
+}
+
+// Traceparent 生成 traceparent 请求头, 如 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func (self SpanContext) Traceparent() string {
+	flags := "00"
+	if self.Sampled {
+		flags = "01"
+	}
+
+	return fmt.Sprintf("00-%s-%s-%s", self.TraceID, self.SpanID, flags)
+}
+
+// ParseTraceparent 解析 traceparent 请求头, 格式不正确或者 ID 全为 0 时返回 false
+// 兼容更高的版本, 只解析前四个字段
+func ParseTraceparent(value string) (SpanContext, bool) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	if parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return sc, false
+	}
+
+	version, err := hex.DecodeString(parts[0])
+	if err != nil || len(version) != 1 {
+		return sc, false
+	}
+	if _, err = hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil || strings.ToLower(parts[1]) != parts[1] {
+		return sc, false
+	}
+	if _, err = hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil || strings.ToLower(parts[2]) != parts[2] {
+		return sc, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, false
+	}
+	sc.Sampled = flags[0]&0x01 == 0x01
+
+	return sc, sc.IsValid()
+}
+
+// ParseTracestate 解析 tracestate 请求头, 去掉格式不正确的键值对, 最多保留 32 个
+func ParseTracestate(value string) string {
+	members := make([]string, 0)
+	for _, member := range strings.Split(value, ",") {
+		member = strings.TrimSpace(member)
+		key, val, ok := strings.Cut(member, "=")
+		if !ok || key == "" || val == "" || strings.ContainsAny(member, " \t") {
+			continue
+		}
+		members = append(members, member)
+		if len(members) == maxTraceStateMembers {
+			break
+		}
+	}
+
+	return strings.Join(members, ",")
+}
+
+// Extract 从请求头中读取上游服务传递的链路信息
+func Extract(header http.Header) (SpanContext, bool) {
+	sc, ok := ParseTraceparent(header.Get(HeaderTraceparent))
+	if !ok {
+		return sc, false
+	}
+	sc.TraceState = ParseTracestate(strings.Join(header.Values(HeaderTracestate), ","))
+
+	return sc, true
+}
+
+// Inject 把 span 的链路信息写入请求头, 传递给下游服务
+func Inject(span *Span, header http.Header) {
+	if span == nil {
+		return
+	}
+
+	sc := span.SpanContext()
+	header.Set(HeaderTraceparent, sc.Traceparent())
+	if sc.TraceState != "" {
+		header.Set(HeaderTracestate, sc.TraceState)
+	} else {
+		header.Del(HeaderTracestate)
+	}
+}
@@ -0,0 +1,137 @@
+package ginatrace
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const (
+	testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testSpanID  = "00f067aa0ba902b7"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		wantOk      bool
+		wantSampled bool
+	}{
+		{name: "采样", value: "00-" + testTraceID + "-" + testSpanID + "-01", wantOk: true, wantSampled: true},
+		{name: "未采样", value: "00-" + testTraceID + "-" + testSpanID + "-00", wantOk: true},
+		{name: "其他标志位", value: "00-" + testTraceID + "-" + testSpanID + "-03", wantOk: true, wantSampled: true},
+		{name: "前后有空格", value: " 00-" + testTraceID + "-" + testSpanID + "-01 ", wantOk: true, wantSampled: true},
+		{name: "更高的版本可以有更多字段", value: "01-" + testTraceID + "-" + testSpanID + "-01-extra", wantOk: true, wantSampled: true},
+		{name: "版本 00 不能有更多字段", value: "00-" + testTraceID + "-" + testSpanID + "-01-extra"},
+		{name: "版本 ff 不合法", value: "ff-" + testTraceID + "-" + testSpanID + "-01"},
+		{name: "版本不是十六进制", value: "zz-" + testTraceID + "-" + testSpanID + "-01"},
+		{name: "trace id 全为 0", value: "00-" + strings.Repeat("0", 32) + "-" + testSpanID + "-01"},
+		{name: "span id 全为 0", value: "00-" + testTraceID + "-" + strings.Repeat("0", 16) + "-01"},
+		{name: "trace id 大写", value: "00-" + strings.ToUpper(testTraceID) + "-" + testSpanID + "-01"},
+		{name: "span id 不是十六进制", value: "00-" + testTraceID + "-00f067aa0ba902bz-01"},
+		{name: "trace id 长度不正确", value: "00-" + testTraceID[:30] + "-" + testSpanID + "-01"},
+		{name: "标志位不是十六进制", value: "00-" + testTraceID + "-" + testSpanID + "-0x"},
+		{name: "字段不足", value: "00-" + testTraceID + "-" + testSpanID},
+		{name: "为空", value: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, ok := ParseTraceparent(tt.value)
+			if ok != tt.wantOk {
+				t.Fatalf("解析结果为 %v, 应该为 %v", ok, tt.wantOk)
+			}
+			if !ok {
+				return
+			}
+			if sc.TraceID.String() != testTraceID || sc.SpanID.String() != testSpanID || sc.Sampled != tt.wantSampled {
+				t.Fatalf("解析为 %+v", sc)
+			}
+		})
+	}
+}
+
+func TestParseTracestate(t *testing.T) {
+	many := make([]string, 40)
+	for i := range many {
+		many[i] = fmt.Sprintf("k%d=v", i)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "原样保留", value: "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE", want: "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE"},
+		{name: "去掉多余的空格和空的成员", value: " rojo=1 , ,congo=2", want: "rojo=1,congo=2"},
+		{name: "去掉格式不正确的成员", value: "rojo,=1,congo=,a=b c,ok=1", want: "ok=1"},
+		{name: "最多保留 32 个", value: strings.Join(many, ","), want: strings.Join(many[:maxTraceStateMembers], ",")},
+		{name: "为空", value: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseTracestate(tt.value); got != tt.want {
+				t.Fatalf("解析为 %q, 应该为 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	traceparent := "00-" + testTraceID + "-" + testSpanID + "-01"
+	tests := []struct {
+		name      string
+		header    http.Header
+		wantOk    bool
+		wantState string
+	}{
+		{name: "没有链路信息", header: http.Header{}},
+		{name: "traceparent 不合法", header: http.Header{"Traceparent": {"invalid"}, "Tracestate": {"a=1"}}},
+		{name: "只有 traceparent", header: http.Header{"Traceparent": {traceparent}}, wantOk: true},
+		{
+			name:      "合并多个 tracestate",
+			header:    http.Header{"Traceparent": {traceparent}, "Tracestate": {"a=1,b=2", "c=3"}},
+			wantOk:    true,
+			wantState: "a=1,b=2,c=3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, ok := Extract(tt.header)
+			if ok != tt.wantOk {
+				t.Fatalf("解析结果为 %v, 应该为 %v", ok, tt.wantOk)
+			}
+			if ok && (sc.TraceID.String() != testTraceID || sc.TraceState != tt.wantState) {
+				t.Fatalf("解析为 %+v", sc)
+			}
+		})
+	}
+}
+
+// 下游服务收到的链路信息与当前 span 一致, 并继承上游的 tracestate
+func TestInjectExtract(t *testing.T) {
+	upstream, _ := ParseTraceparent("00-" + testTraceID + "-" + testSpanID + "-01")
+	upstream.TraceState = "a=1"
+	_, span := Start(context.Background(), "test", WithRemoteParent(upstream))
+
+	header := http.Header{}
+	header.Set(HeaderTracestate, "stale=1")
+	Inject(span, header)
+	sc, ok := Extract(header)
+	if !ok {
+		t.Fatalf("无法解析注入的请求头 %v", header)
+	}
+	if sc.TraceID != upstream.TraceID || sc.SpanID != span.SpanContext().SpanID || !sc.Sampled || sc.TraceState != "a=1" {
+		t.Fatalf("解析为 %+v, span 为 %+v", sc, span.SpanContext())
+	}
+	if span.Parent != upstream.SpanID {
+		t.Fatalf("父 span 为 %s, 应该为 %s", span.Parent, upstream.SpanID)
+	}
+
+	Inject(nil, header)
+	if header.Get(HeaderTraceparent) != span.SpanContext().Traceparent() {
+		t.Fatal("span 为 nil 时不应该修改请求头")
+	}
+}
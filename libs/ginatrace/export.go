@@ -0,0 +1,206 @@
+package ginatrace
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/soryetong/greasyx/console"
+	"github.com/soryetong/greasyx/ginahelper"
+)
+
+const (
+	// 等待导出的 span 的最大数量, 超过后丢弃新的 span
+	queueSize = 2048
+	// 每次最多导出的 span 数量
+	batchSize = 512
+	// 导出的间隔
+	exportInterval = 5 * time.Second
+)
+
+// Exporter 导出 span, Export 在同一个协程中按顺序调用
+type Exporter interface {
+	Export(ctx context.Context, spans []*Span) error
+	Shutdown(ctx context.Context) error
+}
+
+type processor struct {
+	exporter Exporter
+	queue    chan *Span
+	flush    chan chan struct{}
+	done     chan struct{}
+}
+
+var (
+	current   atomic.Pointer[processor]
+	setMu     sync.Mutex
+	dropCount atomic.Uint64
+)
+
+// SetExporter 设置导出 span 的 Exporter 并开始批量导出, 已经设置过时先关闭原来的 Exporter
+func SetExporter(exporter Exporter) {
+	setMu.Lock()
+	defer setMu.Unlock()
+
+	if old := current.Load(); old != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), exportInterval)
+		old.shutdown(ctx)
+		cancel()
+	}
+
+	p := &processor{
+		exporter: exporter,
+		queue:    make(chan *Span, queueSize),
+		flush:    make(chan chan struct{}),
+		done:     make(chan struct{}),
+	}
+	current.Store(p)
+	ginahelper.SafeGo(p.run)
+}
+
+// Shutdown 导出剩余的 span 并关闭 Exporter, 一般在程序退出前调用
+func Shutdown(ctx context.Context) error {
+	setMu.Lock()
+	defer setMu.Unlock()
+
+	p := current.Swap(nil)
+	if p == nil {
+		return nil
+	}
+
+	return p.shutdown(ctx)
+}
+
+// Dropped 因队列已满被丢弃的 span 数量
+func Dropped() uint64 {
+	return dropCount.Load()
+}
+
+func enqueue(span *Span) {
+	p := current.Load()
+	if p == nil {
+		return
+	}
+
+	select {
+	case p.queue <- span:
+	default:
+		dropCount.Add(1)
+	}
+}
+
+func (self *processor) run() {
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, batchSize)
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), exportInterval)
+		defer cancel()
+		if err := self.exporter.Export(ctx, batch); err != nil {
+			console.Echo.Errorf("❌ 错误: 导出 %d 个 span 失败: %s", len(batch), err)
+		}
+		batch = make([]*Span, 0, batchSize)
+	}
+
+	for {
+		select {
+		case span := <-self.queue:
+			batch = append(batch, span)
+			if len(batch) >= batchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case done := <-self.flush:
+			for len(self.queue) > 0 {
+				batch = append(batch, <-self.queue)
+				if len(batch) >= batchSize {
+					export()
+				}
+			}
+			export()
+			close(done)
+			return
+		}
+	}
+}
+
+func (self *processor) shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case self.flush <- done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return self.exporter.Shutdown(ctx)
+}
+
+// StdoutExporter 以 JSON 格式逐行输出 span, 一般用于本地调试
+type StdoutExporter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewStdoutExporter 输出到 w, 如 os.Stdout
+func NewStdoutExporter(w io.Writer) *StdoutExporter {
+	return &StdoutExporter{enc: json.NewEncoder(w)}
+}
+
+type stdoutSpan struct {
+	TraceId    string         `json:"trace_id"`
+	SpanId     string         `json:"span_id"`
+	ParentId   string         `json:"parent_id,omitempty"`
+	Name       string         `json:"name"`
+	Kind       SpanKind       `json:"kind"`
+	Start      time.Time      `json:"start"`
+	Duration   string         `json:"duration"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+	Events     []Event        `json:"events,omitempty"`
+	Status     StatusCode     `json:"status"`
+	Message    string         `json:"message,omitempty"`
+}
+
+func (self *StdoutExporter) Export(ctx context.Context, spans []*Span) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	for _, span := range spans {
+		item := stdoutSpan{
+			TraceId:    span.sc.TraceID.String(),
+			SpanId:     span.sc.SpanID.String(),
+			Name:       span.Name,
+			Kind:       span.Kind,
+			Start:      span.StartTime,
+			Duration:   span.EndTime.Sub(span.StartTime).String(),
+			Attributes: span.Attributes,
+			Events:     span.Events,
+			Status:     span.Status,
+			Message:    span.StatusMessage,
+		}
+		if span.Parent.IsValid() {
+			item.ParentId = span.Parent.String()
+		}
+		if err := self.enc.Encode(item); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (self *StdoutExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
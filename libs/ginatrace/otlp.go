@@ -0,0 +1,194 @@
+package ginatrace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultOTLPEndpoint OTLP/HTTP 收集器默认的地址
+const DefaultOTLPEndpoint = "http://127.0.0.1:4318/v1/traces"
+
+// OTLPConfig OTLP/HTTP 导出的配置
+type OTLPConfig struct {
+	Endpoint    string            // 收集器的地址, 如 http://otel-collector:4318/v1/traces
+	Headers     map[string]string // 额外的请求头, 如鉴权信息
+	ServiceName string            // 服务名称, 对应 service.name 属性
+	Timeout     time.Duration     // 每次导出的超时时间, 默认 10 秒
+}
+
+// OTLPExporter 以 JSON 编码通过 OTLP/HTTP 导出 span, 兼容 OpenTelemetry Collector、Jaeger 和 Tempo 等
+type OTLPExporter struct {
+	config OTLPConfig
+	client *http.Client
+}
+
+// NewOTLPExporter 创建 OTLP/HTTP 的 Exporter
+func NewOTLPExporter(config OTLPConfig) *OTLPExporter {
+	if config.Endpoint == "" {
+		config.Endpoint = DefaultOTLPEndpoint
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+
+	return &OTLPExporter{config: config, client: &http.Client{Timeout: config.Timeout}}
+}
+
+func (self *OTLPExporter) Export(ctx context.Context, spans []*Span) error {
+	body, err := json.Marshal(self.encode(spans))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, self.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range self.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := self.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("收集器返回 %d: %s", resp.StatusCode, msg)
+	}
+
+	return nil
+}
+
+func (self *OTLPExporter) Shutdown(ctx context.Context) error {
+	self.client.CloseIdleConnections()
+	return nil
+}
+
+// 以下为 OTLP 的 JSON 编码, ID 使用十六进制, 时间使用纳秒的字符串
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceId           string         `json:"traceId"`
+	SpanId            string         `json:"spanId"`
+	TraceState        string         `json:"traceState,omitempty"`
+	ParentSpanId      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    StatusCode `json:"code,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+func (self *OTLPExporter) encode(spans []*Span) otlpRequest {
+	list := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		item := otlpSpan{
+			TraceId:           span.sc.TraceID.String(),
+			SpanId:            span.sc.SpanID.String(),
+			TraceState:        span.sc.TraceState,
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: unixNano(span.StartTime),
+			EndTimeUnixNano:   unixNano(span.EndTime),
+			Attributes:        otlpAttributes(span.Attributes),
+			Status:            otlpStatus{Code: span.Status, Message: span.StatusMessage},
+		}
+		if span.Parent.IsValid() {
+			item.ParentSpanId = span.Parent.String()
+		}
+		for _, event := range span.Events {
+			item.Events = append(item.Events, otlpEvent{
+				TimeUnixNano: unixNano(event.Time),
+				Name:         event.Name,
+				Attributes:   otlpAttributes(event.Attributes),
+			})
+		}
+		list = append(list, item)
+	}
+
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: otlpAttributes(map[string]any{
+			"service.name":       self.config.ServiceName,
+			"telemetry.sdk.name": "greasyx",
+		})},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "github.com/soryetong/greasyx/libs/ginatrace"},
+			Spans: list,
+		}},
+	}}}
+}
+
+func otlpAttributes(attrs map[string]any) []otlpKeyValue {
+	list := make([]otlpKeyValue, 0, len(attrs))
+	for key, value := range attrs {
+		var v map[string]any
+		switch val := value.(type) {
+		case string:
+			v = map[string]any{"stringValue": val}
+		case bool:
+			v = map[string]any{"boolValue": val}
+		case int:
+			v = map[string]any{"intValue": strconv.Itoa(val)}
+		case int64:
+			v = map[string]any{"intValue": strconv.FormatInt(val, 10)}
+		case float64:
+			v = map[string]any{"doubleValue": val}
+		default:
+			v = map[string]any{"stringValue": fmt.Sprint(val)}
+		}
+		list = append(list, otlpKeyValue{Key: key, Value: v})
+	}
+
+	return list
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
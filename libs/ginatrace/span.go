@@ -0,0 +1,248 @@
+package ginatrace
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SpanKind span 的类型, 与 OpenTelemetry 的定义一致
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = iota + 1 // 服务内部的操作
+	SpanKindServer                       // 处理上游的请求
+	SpanKindClient                       // 请求下游的服务, 如数据库、Redis 和 HTTP 请求
+)
+
+// StatusCode span 的状态
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = iota
+	StatusOk
+	StatusError
+)
+
+// Event span 中发生的事件, 如错误
+type Event struct {
+	Name       string
+	Time       time.Time
+	Attributes map[string]any
+}
+
+// Span 一次操作的耗时和属性, 所有方法都可以在 nil 上调用, 方便在没有链路信息时直接使用
+type Span struct {
+	Name          string
+	Kind          SpanKind
+	Parent        SpanID // 为空时是链路的第一个 span
+	StartTime     time.Time
+	EndTime       time.Time
+	Attributes    map[string]any
+	Events        []Event
+	Status        StatusCode
+	StatusMessage string
+
+	sc        SpanContext
+	mu        sync.Mutex
+	ended     bool
+	discarded bool
+}
+
+// 采样率, 只对链路的第一个 span 生效, 之后的 span 跟随上游的采样结果
+var sampleRatio atomic.Uint64
+
+func init() {
+	SetSampleRatio(1)
+}
+
+// SetSampleRatio 设置采样率, 0 到 1 之间, 默认为 1 即全部采样
+func SetSampleRatio(ratio float64) {
+	ratio = min(max(ratio, 0), 1)
+	sampleRatio.Store(uint64(ratio * float64(1<<53)))
+}
+
+func sampled() bool {
+	return rand.Uint64N(1<<53) < sampleRatio.Load()
+}
+
+type spanKey struct{}
+
+// GinSpanKey 中间件同时把 span 保存在 gin.Context 中, 以便直接使用 gin.Context 作为 context
+const GinSpanKey = "gina_span"
+
+// StartOption 创建 span 时的可选配置
+type StartOption func(*startOptions)
+
+type startOptions struct {
+	kind   SpanKind
+	remote *SpanContext
+	attrs  map[string]any
+}
+
+// WithKind span 的类型, 默认为 SpanKindInternal
+func WithKind(kind SpanKind) StartOption {
+	return func(o *startOptions) {
+		o.kind = kind
+	}
+}
+
+// WithRemoteParent 以上游服务传递的链路信息作为父 span, 优先于 ctx 中的 span
+func WithRemoteParent(sc SpanContext) StartOption {
+	return func(o *startOptions) {
+		o.remote = &sc
+	}
+}
+
+// WithAttributes span 的初始属性
+func WithAttributes(attrs map[string]any) StartOption {
+	return func(o *startOptions) {
+		o.attrs = attrs
+	}
+}
+
+// Start 创建 span, ctx 中有 span 时作为它的子 span, 否则开始一条新的链路, 需要调用 End 结束
+func Start(ctx context.Context, name string, opts ...StartOption) (context.Context, *Span) {
+	options := startOptions{kind: SpanKindInternal}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	span := &Span{
+		Name:       name,
+		Kind:       options.kind,
+		StartTime:  time.Now(),
+		Attributes: make(map[string]any, len(options.attrs)),
+	}
+	for key, value := range options.attrs {
+		span.Attributes[key] = value
+	}
+
+	var parent SpanContext
+	if options.remote != nil {
+		parent = *options.remote
+	} else if current := SpanFromContext(ctx); current != nil {
+		parent = current.sc
+	}
+	if parent.IsValid() {
+		span.sc = SpanContext{TraceID: parent.TraceID, Sampled: parent.Sampled, TraceState: parent.TraceState}
+		span.Parent = parent.SpanID
+	} else {
+		span.sc = SpanContext{TraceID: newTraceID(), Sampled: sampled()}
+	}
+	span.sc.SpanID = newSpanID()
+
+	return ContextWithSpan(ctx, span), span
+}
+
+// ContextWithSpan 把 span 保存到 ctx 中
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext 获取 ctx 中的 span, 兼容 gin.Context, 没有时返回 nil
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	if span, ok := ctx.Value(spanKey{}).(*Span); ok {
+		return span
+	}
+	if span, ok := ctx.Value(GinSpanKey).(*Span); ok {
+		return span
+	}
+
+	return nil
+}
+
+// SpanContext 在服务之间传递的链路信息
+func (self *Span) SpanContext() SpanContext {
+	if self == nil {
+		return SpanContext{}
+	}
+
+	return self.sc
+}
+
+// TraceID 链路 ID 的十六进制字符串, 用于日志关联
+func (self *Span) TraceID() string {
+	if self == nil {
+		return ""
+	}
+
+	return self.sc.TraceID.String()
+}
+
+// SetAttr 设置属性, 值支持字符串、整数、浮点数和布尔值, 其他类型导出时转为字符串
+func (self *Span) SetAttr(key string, value any) {
+	if self == nil {
+		return
+	}
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.Attributes[key] = value
+}
+
+// RecordError 记录错误, 同时把 span 的状态设置为错误
+func (self *Span) RecordError(err error) {
+	if self == nil || err == nil {
+		return
+	}
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.Events = append(self.Events, Event{
+		Name:       "exception",
+		Time:       time.Now(),
+		Attributes: map[string]any{"exception.message": err.Error()},
+	})
+	self.Status = StatusError
+	self.StatusMessage = err.Error()
+}
+
+// SetStatus 设置 span 的状态
+func (self *Span) SetStatus(code StatusCode, msg string) {
+	if self == nil {
+		return
+	}
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.Status = code
+	self.StatusMessage = msg
+}
+
+// Discard 放弃该 span, 结束后不会导出, 如数据库驱动不支持某个操作而改用其他方式执行时
+func (self *Span) Discard() {
+	if self == nil {
+		return
+	}
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.discarded = true
+}
+
+// End 结束 span, 采样的 span 会交给 Exporter 导出, 多次调用只有第一次生效
+func (self *Span) End() {
+	if self == nil {
+		return
+	}
+
+	self.mu.Lock()
+	if self.ended {
+		self.mu.Unlock()
+		return
+	}
+	self.ended = true
+	self.EndTime = time.Now()
+	export := self.sc.Sampled && !self.discarded
+	self.mu.Unlock()
+
+	if export {
+		enqueue(self)
+	}
+}
@@ -0,0 +1,80 @@
+package adminmodule
+
+import (
+	"context"
+	"errors"
+
+	"github.com/soryetong/greasyx/console"
+	"github.com/soryetong/greasyx/gina"
+	"github.com/soryetong/greasyx/ginahelper"
+	"github.com/soryetong/greasyx/modules/httpmodule"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	console.AppendModule(&adminModule{}, adminCmd)
+}
+
+var adminCmd = &cobra.Command{
+	Use:   "Admin",
+	Short: "Init Admin",
+	Long:  `加载Admin模块之后，会在 Admin.Addr 上启动运维服务，提供 pprof、构建信息、配置、模块和路由等运行时信息`,
+}
+
+// DefaultAddr 默认只监听本机地址
+const DefaultAddr = "127.0.0.1:6060"
+
+type adminModule struct{}
+
+func (self *adminModule) Name() string {
+	return "Admin"
+}
+
+func (self *adminModule) DependsOn() []string {
+	return []string{gina.ModuleName}
+}
+
+// 运维服务作为服务注册, 和业务服务使用不同的端口
+func (self *adminModule) Init() error {
+	viper.SetDefault("Admin.Addr", DefaultAddr)
+	addr := viper.GetString("Admin.Addr")
+	token := viper.GetString("Admin.Token")
+	if token == "" && !ginahelper.IsLoopback(addr) {
+		return errors.New("Admin.Addr 不是本机地址时必须配置 Admin.Token")
+	}
+
+	gina.Register(&adminServer{addr: addr, token: token})
+	console.Echo.Infof("✅ 提示: Admin模块加载成功, 运维服务将监听 %s\n", addr)
+
+	return nil
+}
+
+func (self *adminModule) ConfigSchema() []gina.ConfigField {
+	return []gina.ConfigField{
+		{Key: "Admin.Addr", Type: gina.ConfigTypeString},
+		{Key: "Admin.Token", Type: gina.ConfigTypeString},
+	}
+}
+
+func (self *adminModule) Close() error {
+	return nil
+}
+
+type adminServer struct {
+	*gina.IServer
+
+	addr       string
+	token      string
+	httpModule httpmodule.IHttp
+}
+
+// pprof 的 profile 和 trace 默认采集 30 秒, 不限制写入响应的时间
+func (self *adminServer) OnStart() error {
+	self.httpModule.Init(self, self.addr, 0, newRouter(self.token), httpmodule.WithWriteTimeout(0))
+	return self.httpModule.Start()
+}
+
+func (self *adminServer) OnStop(ctx context.Context) error {
+	return self.httpModule.Stop(ctx)
+}
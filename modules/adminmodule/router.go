@@ -0,0 +1,188 @@
+package adminmodule
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/soryetong/greasyx/console"
+	"github.com/soryetong/greasyx/gina"
+	"github.com/soryetong/greasyx/libs/ginaerror"
+	"github.com/soryetong/greasyx/modules/httpmodule"
+)
+
+// 进程的启动时间
+var startTime = time.Now()
+
+// 所有接口都在 /debug 下, goroutine 和 heap 的快照通过 /debug/pprof/goroutine?debug=2 和 /debug/pprof/heap 获取
+func newRouter(token string) *gin.Engine {
+	engine := gin.New()
+	engine.Use(gin.Recovery(), auth(token))
+
+	group := engine.Group("/debug")
+	group.GET("/build", buildInfo)
+	group.GET("/runtime", runtimeInfo)
+	group.GET("/config", config)
+	group.GET("/modules", modules)
+	group.GET("/routes", routes)
+
+	group.GET("/pprof/", gin.WrapF(pprof.Index))
+	group.GET("/pprof/cmdline", gin.WrapF(pprof.Cmdline))
+	group.GET("/pprof/profile", gin.WrapF(pprof.Profile))
+	group.GET("/pprof/symbol", gin.WrapF(pprof.Symbol))
+	group.POST("/pprof/symbol", gin.WrapF(pprof.Symbol))
+	group.GET("/pprof/trace", gin.WrapF(pprof.Trace))
+	group.GET("/pprof/:name", func(ctx *gin.Context) {
+		pprof.Handler(ctx.Param("name")).ServeHTTP(ctx.Writer, ctx.Request)
+	})
+
+	return engine
+}
+
+// 配置了 Admin.Token 时校验令牌, 支持 Authorization: Bearer <token>、X-Admin-Token 请求头和 token 参数
+// 通过参数传递是为了兼容 go tool pprof 和浏览器直接访问
+func auth(token string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if token == "" {
+			ctx.Next()
+			return
+		}
+
+		given := strings.TrimPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+		if given == "" {
+			given = ctx.GetHeader("X-Admin-Token")
+		}
+		if given == "" {
+			given = ctx.Query("token")
+		}
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			console.Echo.Warnf("⚠️ 警告: 运维服务收到未授权的请求: %s %s, 来源: %s\n", ctx.Request.Method, ctx.Request.URL.Path, ctx.ClientIP())
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gina.Response{
+				Code: ginaerror.NoAuth,
+				Msg:  ginaerror.GetErrorMessage(ginaerror.NoAuth),
+			})
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+type buildResp struct {
+	GoVersion string            `json:"go_version"`
+	Path      string            `json:"path"`
+	Version   string            `json:"version"`
+	Settings  map[string]string `json:"settings"` // 如 vcs.revision、vcs.time、GOOS
+	Deps      []string          `json:"deps"`
+	Pid       int               `json:"pid"`
+	Hostname  string            `json:"hostname"`
+	StartTime time.Time         `json:"start_time"`
+	Uptime    string            `json:"uptime"`
+}
+
+func buildInfo(ctx *gin.Context) {
+	resp := buildResp{
+		GoVersion: runtime.Version(),
+		Settings:  make(map[string]string),
+		Pid:       os.Getpid(),
+		StartTime: startTime,
+		Uptime:    time.Since(startTime).Round(time.Second).String(),
+	}
+	resp.Hostname, _ = os.Hostname()
+	if info, ok := debug.ReadBuildInfo(); ok {
+		resp.Path = info.Main.Path
+		resp.Version = info.Main.Version
+		for _, setting := range info.Settings {
+			resp.Settings[setting.Key] = setting.Value
+		}
+		for _, dep := range info.Deps {
+			resp.Deps = append(resp.Deps, dep.Path+"@"+dep.Version)
+		}
+	}
+
+	gina.Success(ctx, resp)
+}
+
+type runtimeResp struct {
+	Goroutines   int    `json:"goroutines"`
+	GoMaxProcs   int    `json:"gomaxprocs"`
+	NumCPU       int    `json:"num_cpu"`
+	HeapAlloc    uint64 `json:"heap_alloc"`
+	HeapInuse    uint64 `json:"heap_inuse"`
+	HeapObjects  uint64 `json:"heap_objects"`
+	Sys          uint64 `json:"sys"`
+	NumGC        uint32 `json:"num_gc"`
+	PauseTotalNs uint64 `json:"pause_total_ns"`
+}
+
+func runtimeInfo(ctx *gin.Context) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	gina.Success(ctx, runtimeResp{
+		Goroutines:   runtime.NumGoroutine(),
+		GoMaxProcs:   runtime.GOMAXPROCS(0),
+		NumCPU:       runtime.NumCPU(),
+		HeapAlloc:    mem.HeapAlloc,
+		HeapInuse:    mem.HeapInuse,
+		HeapObjects:  mem.HeapObjects,
+		Sys:          mem.Sys,
+		NumGC:        mem.NumGC,
+		PauseTotalNs: mem.PauseTotalNs,
+	})
+}
+
+// 当前生效的配置, 密码等敏感配置被隐藏
+func config(ctx *gin.Context) {
+	gina.Success(ctx, gina.RedactedConfig())
+}
+
+type moduleResp struct {
+	Name      string   `json:"name"`
+	DependsOn []string `json:"depends_on"`
+}
+
+// 已加载的模块, 按加载顺序排列
+func modules(ctx *gin.Context) {
+	list := make([]moduleResp, 0)
+	for _, module := range console.LoadedModules() {
+		list = append(list, moduleResp{Name: module.Name(), DependsOn: module.DependsOn()})
+	}
+
+	gina.Success(ctx, list)
+}
+
+type routeResp struct {
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Handler string `json:"handler"`
+}
+
+type serverResp struct {
+	Name      string      `json:"name"`
+	Listeners []string    `json:"listeners"`
+	Routes    []routeResp `json:"routes"`
+}
+
+// 当前进程中所有 IHttp 服务注册的路由
+func routes(ctx *gin.Context) {
+	list := make([]serverResp, 0)
+	for _, srv := range httpmodule.Instances() {
+		item := serverResp{Name: srv.Name(), Routes: make([]routeResp, 0)}
+		for _, listener := range srv.Listeners() {
+			item.Listeners = append(item.Listeners, listener.String())
+		}
+		for _, route := range srv.Routes() {
+			item.Routes = append(item.Routes, routeResp{Method: route.Method, Path: route.Path, Handler: route.Handler})
+		}
+		list = append(list, item)
+	}
+
+	gina.Success(ctx, list)
+}
@@ -37,6 +37,19 @@ type Cache struct {
 	cleanerStop    chan struct{}
 	cleanerRunning atomic.Bool
 	cleanInterval  time.Duration
+
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	evictions   atomic.Uint64
+	expirations atomic.Uint64
+}
+
+// Stats 缓存的累计统计，用于监控缓存命中率
+type Stats struct {
+	Hits        uint64 // 命中次数
+	Misses      uint64 // 未命中次数，包含已过期的项
+	Evictions   uint64 // 超出容量被淘汰的项数
+	Expirations uint64 // 过期被清理的项数
 }
 
 // DefaultShardCount 是默认分片数量（必须为 2 的幂）
@@ -131,6 +144,7 @@ func (c *Cache) Get(key string) (any, bool) {
 
 	node, exists := s.items[key]
 	if !exists {
+		c.misses.Add(1)
 		return nil, false
 	}
 
@@ -139,8 +153,11 @@ func (c *Cache) Get(key string) (any, bool) {
 		s.removeNode(node)
 		delete(s.items, key)
 		s.count.Add(-1)
+		c.misses.Add(1)
+		c.expirations.Add(1)
 		return nil, false
 	}
+	c.hits.Add(1)
 
 	// 未过期，自动续期并将其移动到链表头部 (标记为最近使用)
 	node.expiresAt = time.Now().Add(node.ttl)
@@ -173,6 +190,16 @@ func (c *Cache) Len() int {
 	return int(total)
 }
 
+// Stats 返回缓存创建以来的累计统计
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Evictions:   c.evictions.Load(),
+		Expirations: c.expirations.Load(),
+	}
+}
+
 // SetOnEvict 设置删除/淘汰时的回调函数
 func (c *Cache) SetOnEvict(cb func(string, any)) {
 	c.onEvict = cb
@@ -262,4 +289,5 @@ func (s *shard) removeLRU() {
 	s.removeNode(s.tail)
 	delete(s.items, s.tail.key)
 	s.count.Add(-1)
+	s.parent.evictions.Add(1)
 }
@@ -37,6 +37,7 @@ func (c *Cache) cleanExpired() {
 				s.removeNode(node)
 				delete(s.items, key)
 				s.count.Add(-1)
+				c.expirations.Add(1)
 			}
 		}
 		s.mu.Unlock()
@@ -1,53 +1,150 @@
 package casbinmodule
 
 import (
+	"fmt"
 	"path/filepath"
 	"runtime"
+	"sync"
 
 	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
 	gormadapter "github.com/casbin/gorm-adapter/v3"
 	"github.com/soryetong/greasyx/console"
 	"github.com/soryetong/greasyx/gina"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 func init() {
-	console.Append(casbinCmd)
+	console.AppendModule(&casbinModule{}, casbinCmd)
 }
 
 var casbinCmd = &cobra.Command{
 	Use:   "Casbin",
 	Short: "Init Casbin",
 	Long:  `加载Casbin模块之后，可以通过 gina.Casbin 进行权限校验`,
-	Run: func(cmd *cobra.Command, args []string) {
-		initCasbin()
-	},
 }
 
-func initCasbin() {
-	modePath := viper.GetString("Casbin.ModePath")
-	if modePath == "" {
-		_, filename, _, _ := runtime.Caller(0)
-		dir := filepath.Dir(filename)
-		modePath = filepath.Join(dir, "rbac_model.conf")
+type casbinModule struct{}
+
+func (self *casbinModule) Name() string {
+	return "Casbin"
+}
+
+// Casbin 的策略存储在数据库中, 需要在 db 模块之后加载
+func (self *casbinModule) DependsOn() []string {
+	return []string{gina.ModuleName, "db"}
+}
+
+func (self *casbinModule) Init() error {
+	return initCasbin()
+}
+
+func (self *casbinModule) ConfigSchema() []gina.ConfigField {
+	return []gina.ConfigField{
+		{Key: "Casbin.ModePath", Type: gina.ConfigTypeString},
+		{Key: "Casbin.DbName", Type: gina.ConfigTypeString},
 	}
+}
+
+func (self *casbinModule) Close() error {
+	return nil
+}
 
+var watchOnce sync.Once
+
+type casbinConfig struct {
+	ModePath string
+}
+
+// New 使用数据库中的策略创建 Enforcer, 不会赋值给 gina.Casbin, 失败时返回错误而不是退出程序
+// modePath 为空时使用内置的 rbac_model.conf
+func New(db *gorm.DB, modePath string) (*casbin.SyncedEnforcer, error) {
+	if db == nil {
+		return nil, fmt.Errorf("Casbin 需要一个可用的数据库连接")
+	}
+	adapter, err := gormadapter.NewAdapterByDB(db)
+	if err != nil {
+		return nil, fmt.Errorf("Casbin 创建策略存储失败: %w", err)
+	}
+	syncedEnforcer, err := casbin.NewSyncedEnforcer(getModePath(modePath), adapter)
+	if err != nil {
+		return nil, fmt.Errorf("Casbin加载失败: %w", err)
+	}
+	if err = syncedEnforcer.LoadPolicy(); err != nil {
+		return nil, fmt.Errorf("Casbin 加载策略失败: %w", err)
+	}
+
+	return syncedEnforcer, nil
+}
+
+func initCasbin() error {
 	db := gina.GMySQL()
 	if db == nil {
 		db = gina.GetGorm(viper.GetString("Casbin.DbName"))
 	}
 	if db == nil {
-		console.Echo.Fatalf("❌ 错误: 你正在加载Casbin模块，但是该模块目前只支持 `MySQL`，请先启用 `gina.GMySQL()`\n")
+		return fmt.Errorf("你正在加载Casbin模块，但是该模块目前只支持 `MySQL`，请先启用 `gina.GMySQL()`")
 	}
-	a, _ := gormadapter.NewAdapterByDB(db)
-	syncedEnforcer, err := casbin.NewSyncedEnforcer(modePath, a)
+	syncedEnforcer, err := New(db, viper.GetString("Casbin.ModePath"))
 	if err != nil {
-		console.Echo.Fatalf("❌ 错误: Casbin加载失败! %v\n", err)
+		return err
 	}
 
-	_ = syncedEnforcer.LoadPolicy()
-
 	gina.Casbin = syncedEnforcer
 	console.Echo.Info("✅ 提示: Casbin模块加载成功, 你可以使用 `gina.Casbin` 进行权限操作\n")
+
+	// 重复初始化时只订阅一次, 热更新始终作用于当前的 gina.Casbin
+	watchOnce.Do(func() {
+		gina.OnConfigChange("Casbin", func(old, new casbinConfig) {
+			if err := reloadModel(getModePath(new.ModePath)); err != nil {
+				gina.Log.Error("[Casbin] 模型热更新失败", zap.Error(err))
+				return
+			}
+			console.Echo.Infof("✅ 提示: Casbin模型热更新成功\n")
+		}, func(new casbinConfig) error {
+			_, err := model.NewModelFromFile(getModePath(new.ModePath))
+			return err
+		})
+	})
+
+	return nil
+}
+
+// 未配置模型文件时使用内置的 rbac_model.conf
+func getModePath(modePath string) string {
+	if modePath != "" {
+		return modePath
+	}
+
+	_, filename, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(filename), "rbac_model.conf")
+}
+
+// 在现有的 Enforcer 上更新模型, gina.Casbin 始终是同一个实例, 其他协程无需重新获取
+// 先在锁外加载新模型和策略, 再持有 Enforcer 的锁替换, 避免请求读到只更新了一半的模型; SyncedEnforcer 的方法会再次加锁, 锁内只能调用 Enforcer 的方法
+func reloadModel(modePath string) error {
+	m, err := model.NewModelFromFile(modePath)
+	if err != nil {
+		return err
+	}
+	loaded, err := casbin.NewEnforcer(m, gina.Casbin.GetAdapter())
+	if err != nil {
+		return fmt.Errorf("Casbin 加载策略失败: %w", err)
+	}
+
+	lock := gina.Casbin.GetLock()
+	lock.Lock()
+	defer lock.Unlock()
+	old := gina.Casbin.Enforcer.GetModel()
+	gina.Casbin.Enforcer.SetModel(loaded.GetModel())
+	if err = gina.Casbin.Enforcer.BuildRoleLinks(); err != nil {
+		gina.Casbin.Enforcer.SetModel(old)
+		_ = gina.Casbin.Enforcer.BuildRoleLinks()
+		return err
+	}
+
+	return nil
 }
@@ -0,0 +1,172 @@
+package cronmodule
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/soryetong/greasyx/console"
+	"github.com/soryetong/greasyx/gina"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	console.AppendModule(&cronModule{}, cronCmd)
+}
+
+var cronCmd = &cobra.Command{
+	Use:   "Cron",
+	Short: "Init Cron",
+	Long:  `加载Cron模块之后，通过 cronmodule.Add 注册的定时任务会作为服务随 Start 一起启动`,
+}
+
+type cronModule struct{}
+
+func (self *cronModule) Name() string {
+	return "Cron"
+}
+
+func (self *cronModule) DependsOn() []string {
+	return []string{gina.ModuleName}
+}
+
+// 定时任务作为服务注册, 停机时等待执行中的任务完成
+func (self *cronModule) Init() error {
+	gina.Register(scheduler)
+	console.Echo.Infof("✅ 提示: Cron模块加载成功, 已注册 %d 个定时任务\n", len(Jobs()))
+
+	return nil
+}
+
+func (self *cronModule) Close() error {
+	return nil
+}
+
+// 支持 6 位带秒的表达式, 也兼容 5 位的标准表达式和 @every 1m 等描述符
+var parser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+var scheduler = newScheduler()
+
+// Add 通过 cron 表达式注册定时任务, 如 Add("0 */5 * * * *", "syncOrders", fn), 任务名不能重复
+func Add(spec, name string, fn JobFunc, opts ...JobOption) error {
+	schedule, err := parser.Parse(spec)
+	if err != nil {
+		return fmt.Errorf("定时任务 %s 的表达式 %s 不合法: %w", name, spec, err)
+	}
+
+	return scheduler.add(newJob(name, spec, fn, opts...), schedule)
+}
+
+// Every 按固定间隔执行定时任务, 间隔从上一次开始执行时计算, 最小精度为 1 秒
+func Every(interval time.Duration, name string, fn JobFunc, opts ...JobOption) error {
+	if interval <= 0 {
+		return fmt.Errorf("定时任务 %s 的间隔必须大于 0", name)
+	}
+
+	return scheduler.add(newJob(name, "@every "+interval.String(), fn, opts...), cron.Every(interval))
+}
+
+// Jobs 获取所有已注册的定时任务名称, 按注册顺序排列
+func Jobs() []string {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	names := make([]string, 0, len(scheduler.jobs))
+	for _, job := range scheduler.jobs {
+		names = append(names, job.name)
+	}
+
+	return names
+}
+
+// History 获取定时任务最近的执行记录, 按时间倒序排列, 任务不存在时返回空
+func History(name string) []Run {
+	scheduler.mu.Lock()
+	job := scheduler.jobMap[name]
+	scheduler.mu.Unlock()
+	if job == nil {
+		return nil
+	}
+
+	return job.history.list()
+}
+
+// Scheduler 定时任务调度器, 实现了 gina.IService 和 gina.IStopper
+type Scheduler struct {
+	mu      sync.Mutex
+	cron    *cron.Cron
+	jobs    []*job
+	jobMap  map[string]*job
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func newScheduler() *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Scheduler{
+		cron:    cron.New(cron.WithParser(parser)),
+		jobMap:  make(map[string]*job),
+		ctx:     ctx,
+		cancel:  cancel,
+		stopped: make(chan struct{}),
+	}
+}
+
+func (self *Scheduler) add(job *job, schedule cron.Schedule) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if _, exists := self.jobMap[job.name]; exists {
+		return fmt.Errorf("定时任务 %s 重复注册", job.name)
+	}
+
+	self.jobs = append(self.jobs, job)
+	self.jobMap[job.name] = job
+	self.cron.Schedule(schedule, cron.FuncJob(func() {
+		job.run(self.ctx)
+	}))
+
+	return nil
+}
+
+// OnStart 启动调度器, 阻塞直到 OnStop 被调用
+func (self *Scheduler) OnStart() error {
+	self.mu.Lock()
+	for _, job := range self.jobs {
+		if job.lockTTL > 0 && gina.Rdb == nil {
+			self.mu.Unlock()
+			return fmt.Errorf("定时任务 %s 需要单实例执行, 请先加载Redis模块", job.name)
+		}
+	}
+	self.mu.Unlock()
+
+	self.cron.Start()
+	gina.Started(self)
+	console.Echo.Infof("✅ 提示: 定时任务调度器启动成功\n")
+	<-self.stopped
+
+	return nil
+}
+
+// OnStop 停止调度新的任务, 并等待执行中的任务完成, ctx 到期后取消所有执行中的任务
+func (self *Scheduler) OnStop(ctx context.Context) error {
+	var err error
+	self.once.Do(func() {
+		defer close(self.stopped)
+		select {
+		case <-self.cron.Stop().Done():
+		case <-ctx.Done():
+			self.cancel()
+			err = fmt.Errorf("等待执行中的定时任务超时: %w", ctx.Err())
+		}
+		self.cancel()
+	})
+
+	return err
+}
@@ -0,0 +1,195 @@
+package cronmodule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/soryetong/greasyx/gina"
+	"go.uber.org/zap"
+)
+
+// 每个任务保留的执行记录条数
+const historySize = 20
+
+// 任务的执行状态
+const (
+	RunStatusSuccess = "success" // 执行成功
+	RunStatusFailed  = "failed"  // 返回了错误
+	RunStatusTimeout = "timeout" // 超过了任务的超时时间
+	RunStatusPanic   = "panic"   // 执行时发生 panic
+	RunStatusSkipped = "skipped" // 上一次执行还未结束, 或者其他实例正在执行
+)
+
+// JobFunc 定时任务, ctx 在任务超时或者服务停机时取消
+type JobFunc func(ctx context.Context) error
+
+// JobOption 定时任务的可选配置
+type JobOption func(*job)
+
+// WithTimeout 设置任务的超时时间, 超时后取消 ctx, 任务需要自行响应 ctx 的取消
+func WithTimeout(timeout time.Duration) JobOption {
+	return func(j *job) {
+		j.timeout = timeout
+	}
+}
+
+// WithSingleton 多实例部署时只允许一个实例执行, 通过 gina.Rdb 加锁实现
+// ttl 为锁的有效期, 为 0 时使用任务的超时时间, 都未设置时为 1 分钟
+func WithSingleton(ttl time.Duration) JobOption {
+	return func(j *job) {
+		j.lockTTL = ttl
+		if j.lockTTL <= 0 {
+			j.lockTTL = -1
+		}
+	}
+}
+
+// WithOverlap 允许上一次执行还未结束时再次执行, 默认跳过本次执行
+func WithOverlap() JobOption {
+	return func(j *job) {
+		j.overlap = true
+	}
+}
+
+// Run 定时任务的一次执行记录
+type Run struct {
+	Name     string        `json:"name"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	Status   string        `json:"status"`
+	Error    string        `json:"error,omitempty"`
+}
+
+type job struct {
+	name    string
+	spec    string
+	fn      JobFunc
+	timeout time.Duration
+	lockTTL time.Duration
+	overlap bool
+
+	running atomic.Bool
+	history *history
+}
+
+func newJob(name, spec string, fn JobFunc, opts ...JobOption) *job {
+	j := &job{name: name, spec: spec, fn: fn, history: &history{}}
+	for _, opt := range opts {
+		opt(j)
+	}
+	if j.lockTTL < 0 {
+		j.lockTTL = j.timeout
+		if j.lockTTL <= 0 {
+			j.lockTTL = time.Minute
+		}
+	}
+
+	return j
+}
+
+func (self *job) run(parent context.Context) {
+	run := Run{Name: self.name, Start: time.Now()}
+	defer func() {
+		run.Duration = time.Since(run.Start)
+		self.history.add(run)
+	}()
+
+	if !self.overlap {
+		if !self.running.CompareAndSwap(false, true) {
+			run.Status = RunStatusSkipped
+			run.Error = "上一次执行还未结束"
+			return
+		}
+		defer self.running.Store(false)
+	}
+
+	ctx := parent
+	if self.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(parent, self.timeout)
+		defer cancel()
+	}
+
+	if self.lockTTL > 0 {
+		unlock, ok, err := lock(ctx, "cron:lock:"+self.name, self.lockTTL)
+		if err != nil {
+			run.Status = RunStatusFailed
+			run.Error = err.Error()
+			gina.Log.Error("[Cron.Lock] 定时任务加锁失败", zap.String("job", self.name), zap.Error(err))
+			return
+		}
+		if !ok {
+			run.Status = RunStatusSkipped
+			run.Error = "其他实例正在执行"
+			return
+		}
+		defer unlock()
+	}
+
+	err := self.call(ctx)
+	switch {
+	case err == nil:
+		run.Status = RunStatusSuccess
+	case errors.Is(err, errPanic):
+		run.Status = RunStatusPanic
+		run.Error = err.Error()
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		run.Status = RunStatusTimeout
+		run.Error = err.Error()
+		gina.Log.Warn("[Cron.Run] 定时任务执行超时", zap.String("job", self.name), zap.Duration("timeout", self.timeout))
+	default:
+		run.Status = RunStatusFailed
+		run.Error = err.Error()
+		gina.Log.Error("[Cron.Run] 定时任务执行失败", zap.String("job", self.name), zap.Error(err))
+	}
+}
+
+var errPanic = errors.New("panic")
+
+// 执行任务并捕获 panic, 避免影响调度器和其他任务
+func (self *job) call(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			gina.Log.Error("[Cron.Run] 定时任务发生panic",
+				zap.String("job", self.name), zap.Any("panic", r), zap.String("stack", string(debug.Stack())))
+			err = fmt.Errorf("%w: %v", errPanic, r)
+		}
+	}()
+
+	return self.fn(ctx)
+}
+
+// 固定长度的执行记录, 新的记录覆盖最旧的记录
+type history struct {
+	mu   sync.Mutex
+	runs []Run
+	next int
+}
+
+func (self *history) add(run Run) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if len(self.runs) < historySize {
+		self.runs = append(self.runs, run)
+		return
+	}
+	self.runs[self.next] = run
+	self.next = (self.next + 1) % historySize
+}
+
+func (self *history) list() []Run {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	runs := make([]Run, 0, len(self.runs))
+	for i := len(self.runs) - 1; i >= 0; i-- {
+		runs = append(runs, self.runs[(self.next+i)%len(self.runs)])
+	}
+
+	return runs
+}
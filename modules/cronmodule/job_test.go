@@ -0,0 +1,230 @@
+package cronmodule
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/soryetong/greasyx/ginatest"
+)
+
+func setup(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	env := ginatest.New(t, nil)
+	return env.Redis()
+}
+
+func TestHistory(t *testing.T) {
+	tests := []struct {
+		name  string
+		count int
+		want  []int // 按时间倒序, 保留的记录序号
+	}{
+		{name: "为空", count: 0, want: []int{}},
+		{name: "未满", count: 3, want: []int{2, 1, 0}},
+		{name: "刚好满", count: historySize, want: sequence(historySize-1, 0)},
+		{name: "覆盖最旧的记录", count: historySize + 5, want: sequence(historySize+4, 5)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &history{}
+			start := time.Now()
+			for i := range tt.count {
+				h.add(Run{Start: start.Add(time.Duration(i) * time.Second)})
+			}
+
+			got := make([]int, 0, tt.count)
+			for _, run := range h.list() {
+				got = append(got, int(run.Start.Sub(start)/time.Second))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("执行记录为 %v, 应该为 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// from 到 to 的倒序序列
+func sequence(from, to int) []int {
+	list := make([]int, 0, from-to+1)
+	for i := from; i >= to; i-- {
+		list = append(list, i)
+	}
+
+	return list
+}
+
+func TestJobRun(t *testing.T) {
+	setup(t)
+	tests := []struct {
+		name       string
+		fn         JobFunc
+		opts       []JobOption
+		wantStatus string
+		wantError  string
+	}{
+		{
+			name:       "成功",
+			fn:         func(ctx context.Context) error { return nil },
+			wantStatus: RunStatusSuccess,
+		},
+		{
+			name:       "返回错误",
+			fn:         func(ctx context.Context) error { return errors.New("boom") },
+			wantStatus: RunStatusFailed,
+			wantError:  "boom",
+		},
+		{
+			name:       "panic",
+			fn:         func(ctx context.Context) error { panic("boom") },
+			wantStatus: RunStatusPanic,
+			wantError:  "panic: boom",
+		},
+		{
+			name: "超时",
+			fn: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+			opts:       []JobOption{WithTimeout(10 * time.Millisecond)},
+			wantStatus: RunStatusTimeout,
+			wantError:  context.DeadlineExceeded.Error(),
+		},
+		{
+			name:       "单实例执行",
+			fn:         func(ctx context.Context) error { return nil },
+			opts:       []JobOption{WithSingleton(time.Minute)},
+			wantStatus: RunStatusSuccess,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := newJob("test", "@every 1s", tt.fn, tt.opts...)
+			j.run(context.Background())
+
+			runs := j.history.list()
+			if len(runs) != 1 {
+				t.Fatalf("执行记录为 %v", runs)
+			}
+			if runs[0].Status != tt.wantStatus || runs[0].Error != tt.wantError {
+				t.Fatalf("执行结果为 %s %q, 应该为 %s %q", runs[0].Status, runs[0].Error, tt.wantStatus, tt.wantError)
+			}
+		})
+	}
+}
+
+// 上一次执行还未结束时跳过, WithOverlap 时允许同时执行
+func TestJobOverlap(t *testing.T) {
+	setup(t)
+	tests := []struct {
+		name       string
+		opts       []JobOption
+		wantStatus string
+	}{
+		{name: "默认跳过", wantStatus: RunStatusSkipped},
+		{name: "允许同时执行", opts: []JobOption{WithOverlap()}, wantStatus: RunStatusSuccess},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started, release := make(chan struct{}, 2), make(chan struct{})
+			j := newJob("overlap", "@every 1s", func(ctx context.Context) error {
+				started <- struct{}{}
+				<-release
+				return nil
+			}, tt.opts...)
+
+			go j.run(context.Background())
+			<-started
+			go j.run(context.Background())
+			if tt.wantStatus == RunStatusSkipped {
+				// 跳过时立即记录, 不会等待上一次执行结束
+				waitRuns(t, j, 1)
+			} else {
+				<-started
+			}
+			close(release)
+			waitRuns(t, j, 2)
+
+			var statuses []string
+			for _, run := range j.history.list() {
+				statuses = append(statuses, run.Status)
+			}
+			slices.Sort(statuses)
+			want := []string{RunStatusSuccess, tt.wantStatus}
+			slices.Sort(want)
+			if !slices.Equal(statuses, want) {
+				t.Fatalf("执行状态为 %v, 应该为 %v", statuses, want)
+			}
+		})
+	}
+}
+
+func waitRuns(t *testing.T, j *job, count int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(j.history.list()) < count {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待 %d 条执行记录超时", count)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// 其他实例持有锁时跳过, 执行完成后只释放自己持有的锁
+func TestJobSingleton(t *testing.T) {
+	mr := setup(t)
+	key := "cron:lock:singleton"
+
+	var ran bool
+	j := newJob("singleton", "@every 1s", func(ctx context.Context) error {
+		ran = true
+		// 锁在执行期间过期, 被其他实例获取
+		mr.FastForward(time.Minute)
+		return mr.Set(key, "other")
+	}, WithSingleton(time.Second))
+
+	_ = mr.Set(key, "locked")
+	j.run(context.Background())
+	if ran || j.history.list()[0].Status != RunStatusSkipped {
+		t.Fatalf("其他实例持有锁时应该跳过, 执行记录为 %v", j.history.list())
+	}
+
+	mr.Del(key)
+	j.run(context.Background())
+	if !ran || j.history.list()[0].Status != RunStatusSuccess {
+		t.Fatalf("获取到锁时应该执行, 执行记录为 %v", j.history.list())
+	}
+	if value, _ := mr.Get(key); value != "other" {
+		t.Fatalf("不应该释放其他实例的锁, 锁的值为 %q", value)
+	}
+
+	released := newJob("singleton", "@every 1s", func(ctx context.Context) error { return nil }, WithSingleton(time.Minute))
+	mr.Del(key)
+	released.run(context.Background())
+	if mr.Exists(key) {
+		t.Fatal("执行完成后应该释放自己持有的锁")
+	}
+}
+
+func TestWithSingletonTTL(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []JobOption
+		want time.Duration
+	}{
+		{name: "未设置", want: 0},
+		{name: "指定有效期", opts: []JobOption{WithSingleton(5 * time.Second)}, want: 5 * time.Second},
+		{name: "使用超时时间", opts: []JobOption{WithSingleton(0), WithTimeout(time.Hour)}, want: time.Hour},
+		{name: "默认为 1 分钟", opts: []JobOption{WithSingleton(0)}, want: time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newJob("ttl", "@every 1s", nil, tt.opts...).lockTTL; got != tt.want {
+				t.Fatalf("锁的有效期为 %s, 应该为 %s", got, tt.want)
+			}
+		})
+	}
+}
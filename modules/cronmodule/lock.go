@@ -0,0 +1,33 @@
+package cronmodule
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/soryetong/greasyx/gina"
+	"github.com/soryetong/greasyx/ginahelper"
+)
+
+// 只释放自己持有的锁, 避免锁过期后误删其他实例的锁
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// 通过 SET NX 加锁, 未获取到锁时 ok 为 false
+func lock(ctx context.Context, key string, ttl time.Duration) (unlock func(), ok bool, err error) {
+	token := ginahelper.RandString(16)
+	ok, err = gina.Rdb.SetNX(ctx, key, token, ttl).Result()
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		_ = unlockScript.Run(ctx, gina.Rdb, []string{key}, token).Err()
+	}, true, nil
+}
@@ -1,7 +1,8 @@
 package dbmodule
 
 import (
-	"encoding/json"
+	"fmt"
+	"strings"
 
 	"github.com/soryetong/greasyx/console"
 	"github.com/soryetong/greasyx/gina"
@@ -10,22 +11,64 @@ import (
 )
 
 func init() {
-	console.Append(dbCmd)
+	console.AppendModule(&dbModule{}, dbCmd)
 }
 
 var dbCmd = &cobra.Command{
 	Use:   "db",
 	Short: "Init DB",
 	Long:  `加载DB模块`,
-	Run: func(cmd *cobra.Command, args []string) {
-		initFunc()
-	},
 }
 
-type dbConfig struct {
+type dbModule struct{}
+
+func (self *dbModule) Name() string {
+	return "db"
+}
+
+func (self *dbModule) DependsOn() []string {
+	return []string{gina.ModuleName}
+}
+
+func (self *dbModule) Init() error {
+	return initFunc()
+}
+
+func (self *dbModule) ConfigSchema() []gina.ConfigField {
+	return []gina.ConfigField{
+		{Key: "Db", Type: gina.ConfigTypeArray, Required: true},
+		{Key: "Db[].Dsn", Type: gina.ConfigTypeString, Required: true},
+		{Key: "Db[].Driver", Type: gina.ConfigTypeString, Required: true, Check: checkDriver},
+		{Key: "Db[].UseOrm", Type: gina.ConfigTypeBool},
+		{Key: "Db[].LogLevel", Type: gina.ConfigTypeInt, Enum: []string{"1", "2", "3"}},
+		{Key: "Db[].EnableLogWriter", Type: gina.ConfigTypeBool},
+		{Key: "Db[].MaxIdleConn", Type: gina.ConfigTypeInt},
+		{Key: "Db[].MaxConn", Type: gina.ConfigTypeInt},
+		{Key: "Db[].SlowThreshold", Type: gina.ConfigTypeInt},
+	}
+}
+
+// Driver 必须以支持的驱动名作为前缀, 如 mysql_master
+func checkDriver(value any) error {
+	driver := strings.ToLower(strings.Split(value.(string), "_")[0])
+	switch driver {
+	case gina.DbTypeMysql, gina.DbTypePostgresql, gina.DbTypeSqlite, gina.DbTypeSqlserver, gina.DbTypeOracle:
+		return nil
+	}
+
+	return fmt.Errorf("不支持的数据库驱动类型: %s", value)
+}
+
+// 每个连接池在打开时都注册了各自的释放函数
+func (self *dbModule) Close() error {
+	return nil
+}
+
+// Config 数据库配置, 对应配置文件中 Db 数组的每一项
+type Config struct {
 	Dsn             string
-	Driver          string
-	UseOrm          bool
+	Driver          string // 驱动名, 支持以驱动名为前缀的多个实例, 如 mysql_master、mysql_slave
+	UseOrm          bool   // true 使用 gorm, false 使用 sqlx
 	LogLevel        int
 	EnableLogWriter bool
 	MaxIdleConn     int
@@ -33,45 +76,31 @@ type dbConfig struct {
 	SlowThreshold   int
 }
 
-func initFunc() {
-	conf := viper.Get(`Db`)
-	confMap, ok := conf.([]interface{})
-	if !ok || len(confMap) == 0 {
-		console.Echo.Fatalf("❌ 错误: 请确保 `Db` 模块的配置符合要求\n")
+func initFunc() error {
+	var confList []Config
+	if err := viper.UnmarshalKey("Db", &confList); err != nil || len(confList) == 0 {
+		return fmt.Errorf("请确保 `Db` 模块的配置符合要求")
 	}
 
 	initMap()
 
-	for _, v := range confMap {
-		dbConfMap, ok := v.(map[string]interface{})
-		if !ok {
-			console.Echo.Errorf("❌ 类型断言错误: 请确保 `Db` 模块的配置符合要求\n")
-			continue
-		}
-
-		jsonData, err := json.Marshal(dbConfMap)
-		if err != nil {
-			console.Echo.Errorf("❌ json.Marshal错误: 请确保 `Db` 模块的配置符合要求\n")
-			continue
-		}
-
-		var dbConf dbConfig
-		if err = json.Unmarshal(jsonData, &dbConf); err != nil {
-			console.Echo.Errorf("❌ json.Unmarshal错误: 请确保 `Db` 模块的配置符合要求\n")
-			continue
-		}
-
+	for _, dbConf := range confList {
 		if dbConf.Driver == "" || dbConf.Dsn == "" {
-			console.Echo.Fatalf("❌ 错误: 你正在加载Db模块，但是你未配置Dsn和Driver，请先添加配置\n")
-			continue
+			return fmt.Errorf("你正在加载Db模块，但是你未配置Dsn和Driver，请先添加配置")
 		}
 
+		var err error
 		if dbConf.UseOrm {
-			initGorm(&dbConf)
+			err = initGorm(&dbConf)
 		} else {
-			initSqlx(&dbConf)
+			err = initSqlx(&dbConf)
+		}
+		if err != nil {
+			return err
 		}
 	}
+
+	return nil
 }
 
 func initMap() {
@@ -1,6 +1,7 @@
 package dbmodule
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
@@ -23,7 +24,8 @@ import (
 
 var om = make(map[string]string)
 
-func initGorm(conf *dbConfig) {
+// Open 按照配置打开 gorm 连接, 不会注册到 gina 中, 失败时返回错误而不是退出程序, 可以在测试或其他程序中单独使用
+func Open(conf *Config) (*gorm.DB, error) {
 	if conf.LogLevel == 0 {
 		conf.LogLevel = 3
 	}
@@ -63,31 +65,57 @@ func initGorm(conf *dbConfig) {
 			DSN: dsn,
 		})
 	case gina.DbTypeOracle:
-		console.Echo.Fatalf("❌ 错误: gorm 暂不支持 Oracle \n")
+		return nil, fmt.Errorf("gorm 暂不支持 Oracle")
 	default:
-		console.Echo.Fatalf("❌ 错误: 不支持的数据库驱动类型: %s\n", conf.Driver)
+		return nil, fmt.Errorf("不支持的数据库驱动类型: %s", conf.Driver)
 	}
 	db, err := gorm.Open(orm, &gorm.Config{
 		Logger: getLogger(conf),
 	})
 	if err != nil {
-		console.Echo.Fatalf("❌ 错误: MySQL连接失败: %s\n", err)
+		return nil, fmt.Errorf("%s 数据库连接失败: %w", conf.Driver, err)
+	}
+	// 连接池已经打开, 之后失败时需要关闭, 否则连接会一直保留
+	sqlDB, err := db.DB()
+	if err != nil {
+		if closer, ok := db.ConnPool.(io.Closer); ok {
+			_ = closer.Close()
+		}
+		return nil, fmt.Errorf("%s 获取连接池失败: %w", conf.Driver, err)
+	}
+	if err = db.Use(&gormTracer{system: strings.ToLower(driverArr[0])}); err != nil {
+		_ = sqlDB.Close()
+		return nil, fmt.Errorf("%s 注册链路追踪失败: %w", conf.Driver, err)
 	}
-
-	sqlDB, _ := db.DB()
 	sqlDB.SetMaxIdleConns(conf.MaxIdleConn)
 	sqlDB.SetMaxOpenConns(conf.MaxConn)
 
+	return db, nil
+}
+
+func initGorm(conf *Config) error {
+	db, err := Open(conf)
+	if err != nil {
+		return err
+	}
+
+	sqlDB, _ := db.DB()
+	console.RegisterCloser("gorm:"+conf.Driver, func(ctx context.Context) error {
+		return sqlDB.Close()
+	})
+
 	gina.SetGorm(conf.Driver, db)
 	name, ok := om[strings.ToLower(conf.Driver)]
 	if !ok {
 		name = fmt.Sprintf("gina.GetGorm(%s)", conf.Driver)
 	}
 	console.Echo.Infof("✅ 提示: `%s` 模块加载成功, 你可以使用 `%s` 进行ORM操作\n", conf.Driver, name)
+
+	return nil
 }
 
 // 切换默认 Logger 使用的 Writer
-func getLogger(conf *dbConfig) logger.Interface {
+func getLogger(conf *Config) logger.Interface {
 	logLevel := conf.LogLevel
 	var logMode logger.LogLevel
 	switch logLevel {
@@ -1,6 +1,7 @@
 package dbmodule
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -11,10 +12,8 @@ import (
 
 var sm = make(map[string]string)
 
-func initSqlx(conf *dbConfig) {
-	if gina.GetSqlx(conf.Driver) != nil {
-		return
-	}
+// OpenSqlx 按照配置打开 sqlx 连接并检查连通性, 不会注册到 gina 中, 失败时返回错误而不是退出程序
+func OpenSqlx(conf *Config) (*sqlx.DB, error) {
 	if conf.MaxIdleConn == 0 {
 		conf.MaxIdleConn = 10
 	}
@@ -24,37 +23,58 @@ func initSqlx(conf *dbConfig) {
 
 	dsn := ensureTimeout(conf.Dsn, "5s")
 	driverArr := strings.Split(conf.Driver, "_")
+	system := strings.ToLower(driverArr[0])
 	var db *sqlx.DB
 	var err error
-	switch strings.ToLower(driverArr[0]) {
+	switch system {
 	case gina.DbTypeMysql:
-		db, err = sqlx.Open("mysql", dsn)
+		db, err = openTracedSqlx("mysql", dsn, system)
 	case gina.DbTypePostgresql:
-		db, err = sqlx.Open("postgres", dsn)
+		db, err = openTracedSqlx("postgres", dsn, system)
 	case gina.DbTypeSqlite:
-		db, err = sqlx.Open("sqlite3", dsn)
+		db, err = openTracedSqlx("sqlite3", dsn, system)
 	case gina.DbTypeSqlserver:
-		db, err = sqlx.Open("sqlserver", dsn)
+		db, err = openTracedSqlx("sqlserver", dsn, system)
 	case gina.DbTypeOracle:
-		db, err = sqlx.Open("oracle", dsn)
+		db, err = openTracedSqlx("oracle", dsn, system)
 	default:
-		console.Echo.Fatalf("❌ 错误: 不支持的数据库驱动类型: %s\n", conf.Driver)
+		return nil, fmt.Errorf("不支持的数据库驱动类型: %s", conf.Driver)
 	}
 
 	if err != nil {
-		console.Echo.Fatalf("❌ 错误: %s 数据库连接失败: %s\n", conf.Driver, err)
+		return nil, fmt.Errorf("%s 数据库连接失败: %w", conf.Driver, err)
 	}
 
 	if err = db.Ping(); err != nil {
-		console.Echo.Fatalf("❌ 错误: %s 数据库无法访问: %s\n", conf.Driver, err)
+		_ = db.Close()
+		return nil, fmt.Errorf("%s 数据库无法访问: %w", conf.Driver, err)
 	}
 
 	db.SetMaxIdleConns(conf.MaxIdleConn)
 	db.SetMaxOpenConns(conf.MaxConn)
+
+	return db, nil
+}
+
+func initSqlx(conf *Config) error {
+	if gina.GetSqlx(conf.Driver) != nil {
+		return nil
+	}
+
+	db, err := OpenSqlx(conf)
+	if err != nil {
+		return err
+	}
+
+	console.RegisterCloser("sqlx:"+conf.Driver, func(ctx context.Context) error {
+		return db.Close()
+	})
 	gina.SetSqlx(conf.Driver, db)
 	name, ok := sm[strings.ToLower(conf.Driver)]
 	if !ok {
 		name = fmt.Sprintf("gina.GetSqlx(%s)", conf.Driver)
 	}
 	console.Echo.Infof("✅ 提示: `%s` 模块加载成功, 你可以使用 `%s` 进行SQL操作\n", conf.Driver, name)
+
+	return nil
 }
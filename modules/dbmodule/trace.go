@@ -0,0 +1,283 @@
+package dbmodule
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/soryetong/greasyx/libs/ginatrace"
+	"gorm.io/gorm"
+)
+
+// 只为 ctx 中有 span 的查询创建子 span, gorm 需要通过 db.WithContext(ctx) 传入 ctx
+func startSpan(ctx context.Context, system, operation string) *ginatrace.Span {
+	if ginatrace.SpanFromContext(ctx) == nil {
+		return nil
+	}
+
+	_, span := ginatrace.Start(ctx, system+" "+operation, ginatrace.WithKind(ginatrace.SpanKindClient), ginatrace.WithAttributes(map[string]any{
+		"db.system":         system,
+		"db.operation.name": operation,
+	}))
+
+	return span
+}
+
+func endSpan(span *ginatrace.Span, err error) {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// gorm 的链路追踪插件, 在每个操作的前后记录 span
+type gormTracer struct {
+	system string
+}
+
+const gormSpanKey = "gina:trace:span"
+
+func (self *gormTracer) Name() string {
+	return "gina:trace"
+}
+
+func (self *gormTracer) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("gina:trace:before_create", self.before("create")),
+		cb.Create().After("gorm:create").Register("gina:trace:after_create", self.after),
+		cb.Query().Before("gorm:query").Register("gina:trace:before_query", self.before("query")),
+		cb.Query().After("gorm:query").Register("gina:trace:after_query", self.after),
+		cb.Update().Before("gorm:update").Register("gina:trace:before_update", self.before("update")),
+		cb.Update().After("gorm:update").Register("gina:trace:after_update", self.after),
+		cb.Delete().Before("gorm:delete").Register("gina:trace:before_delete", self.before("delete")),
+		cb.Delete().After("gorm:delete").Register("gina:trace:after_delete", self.after),
+		cb.Row().Before("gorm:row").Register("gina:trace:before_row", self.before("row")),
+		cb.Row().After("gorm:row").Register("gina:trace:after_row", self.after),
+		cb.Raw().Before("gorm:raw").Register("gina:trace:before_raw", self.before("raw")),
+		cb.Raw().After("gorm:raw").Register("gina:trace:after_raw", self.after),
+	)
+}
+
+func (self *gormTracer) before(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		if span := startSpan(tx.Statement.Context, self.system, operation); span != nil {
+			if tx.Statement.Table != "" {
+				span.SetAttr("db.collection.name", tx.Statement.Table)
+			}
+			tx.InstanceSet(gormSpanKey, span)
+		}
+	}
+}
+
+// 记录带占位符的 SQL, 不包含参数的值
+func (self *gormTracer) after(tx *gorm.DB) {
+	value, ok := tx.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span := value.(*ginatrace.Span)
+	span.SetAttr("db.query.text", tx.Statement.SQL.String())
+	span.SetAttr("db.response.returned_rows", tx.Statement.RowsAffected)
+	endSpan(span, tx.Error)
+}
+
+// 通过包装 database/sql 的驱动为 sqlx 的查询记录 span
+func openTracedSqlx(driverName, dsn, system string) (*sqlx.DB, error) {
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	drv := db.Driver()
+	_ = db.Close()
+
+	var connector driver.Connector = dsnConnector{dsn: dsn, driver: drv}
+	if dc, ok := drv.(driver.DriverContext); ok {
+		if connector, err = dc.OpenConnector(dsn); err != nil {
+			return nil, err
+		}
+	}
+
+	return sqlx.NewDb(sql.OpenDB(&tracedConnector{Connector: connector, system: system}), driverName), nil
+}
+
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (self dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return self.driver.Open(self.dsn)
+}
+
+func (self dsnConnector) Driver() driver.Driver {
+	return self.driver
+}
+
+type tracedConnector struct {
+	driver.Connector
+	system string
+}
+
+func (self *tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := self.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tracedConn{Conn: conn, system: self.system}, nil
+}
+
+// 只记录查询和执行, 事务的提交和回滚不记录, 其他接口透传给原来的连接
+type tracedConn struct {
+	driver.Conn
+	system string
+}
+
+func (self *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := self.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	span := startSpan(ctx, self.system, "query")
+	span.SetAttr("db.query.text", query)
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if errors.Is(err, driver.ErrSkip) {
+		// 驱动不支持时 database/sql 会改用预处理语句执行, 由 tracedStmt 记录
+		span.Discard()
+	}
+	endSpan(span, err)
+
+	return rows, err
+}
+
+func (self *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := self.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	span := startSpan(ctx, self.system, "exec")
+	span.SetAttr("db.query.text", query)
+	result, err := execer.ExecContext(ctx, query, args)
+	if errors.Is(err, driver.ErrSkip) {
+		span.Discard()
+	}
+	endSpan(span, err)
+
+	return result, err
+}
+
+func (self *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := self.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = self.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &tracedStmt{Stmt: stmt, query: query, system: self.system}, nil
+}
+
+func (self *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := self.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+
+	return self.Conn.Begin() //nolint:staticcheck // 驱动未实现 ConnBeginTx 时只能使用 Begin
+}
+
+func (self *tracedConn) Ping(ctx context.Context) error {
+	if pinger, ok := self.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+
+	return nil
+}
+
+func (self *tracedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := self.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+
+	return nil
+}
+
+func (self *tracedConn) IsValid() bool {
+	if validator, ok := self.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+
+	return true
+}
+
+func (self *tracedConn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := self.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+
+	return driver.ErrSkip
+}
+
+type tracedStmt struct {
+	driver.Stmt
+	query  string
+	system string
+}
+
+func (self *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	span := startSpan(ctx, self.system, "query")
+	span.SetAttr("db.query.text", self.query)
+
+	var rows driver.Rows
+	var err error
+	if queryer, ok := self.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		rows, err = self.Stmt.Query(namedToValues(args)) //nolint:staticcheck // 驱动未实现 StmtQueryContext 时只能使用 Query
+	}
+	endSpan(span, err)
+
+	return rows, err
+}
+
+func (self *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	span := startSpan(ctx, self.system, "exec")
+	span.SetAttr("db.query.text", self.query)
+
+	var result driver.Result
+	var err error
+	if execer, ok := self.Stmt.(driver.StmtExecContext); ok {
+		result, err = execer.ExecContext(ctx, args)
+	} else {
+		result, err = self.Stmt.Exec(namedToValues(args)) //nolint:staticcheck // 驱动未实现 StmtExecContext 时只能使用 Exec
+	}
+	endSpan(span, err)
+
+	return result, err
+}
+
+func (self *tracedStmt) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := self.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+
+	return driver.ErrSkip
+}
+
+func namedToValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+
+	return values
+}
@@ -0,0 +1,177 @@
+package dbmodule
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/soryetong/greasyx/libs/ginatrace"
+)
+
+// 记录导出的 span
+type recorder struct {
+	mu    sync.Mutex
+	spans []*ginatrace.Span
+}
+
+func (self *recorder) Export(ctx context.Context, spans []*ginatrace.Span) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.spans = append(self.spans, spans...)
+	return nil
+}
+
+func (self *recorder) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// 执行 fn 并返回其中导出的 span
+func record(t *testing.T, fn func()) []*ginatrace.Span {
+	t.Helper()
+	rec := &recorder{}
+	ginatrace.SetExporter(rec)
+	fn()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ginatrace.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	return rec.spans
+}
+
+func openTestSqlx(t *testing.T) *sqlx.DB {
+	t.Helper()
+	db, err := openTracedSqlx("sqlite3", filepath.Join(t.TempDir(), "test.db"), "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if _, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func TestTracedSqlx(t *testing.T) {
+	db := openTestSqlx(t)
+	tests := []struct {
+		name     string
+		run      func(ctx context.Context) error
+		wantName string
+		wantText string
+		wantErr  bool
+	}{
+		{
+			name: "执行",
+			run: func(ctx context.Context) error {
+				_, err := db.ExecContext(ctx, "INSERT INTO users (name) VALUES (?)", "a")
+				return err
+			},
+			wantName: "sqlite exec",
+			wantText: "INSERT INTO users (name) VALUES (?)",
+		},
+		{
+			name: "查询",
+			run: func(ctx context.Context) error {
+				var names []string
+				return db.SelectContext(ctx, &names, "SELECT name FROM users WHERE id > ?", 0)
+			},
+			wantName: "sqlite query",
+			wantText: "SELECT name FROM users WHERE id > ?",
+		},
+		{
+			name: "没有数据不是错误",
+			run: func(ctx context.Context) error {
+				var name string
+				_ = db.GetContext(ctx, &name, "SELECT name FROM users WHERE id = ?", -1)
+				return nil
+			},
+			wantName: "sqlite query",
+			wantText: "SELECT name FROM users WHERE id = ?",
+		},
+		{
+			name: "错误的语句",
+			run: func(ctx context.Context) error {
+				_, _ = db.QueryContext(ctx, "SELECT * FROM missing")
+				return nil
+			},
+			wantName: "sqlite query",
+			wantText: "SELECT * FROM missing",
+			wantErr:  true,
+		},
+		{
+			name: "预处理语句",
+			run: func(ctx context.Context) error {
+				stmt, err := db.PreparexContext(ctx, "UPDATE users SET name = ? WHERE id = ?")
+				if err != nil {
+					return err
+				}
+				defer stmt.Close()
+				_, err = stmt.ExecContext(ctx, "b", 1)
+				return err
+			},
+			wantName: "sqlite exec",
+			wantText: "UPDATE users SET name = ? WHERE id = ?",
+		},
+		{
+			name: "事务中的语句",
+			run: func(ctx context.Context) error {
+				tx, err := db.BeginTxx(ctx, nil)
+				if err != nil {
+					return err
+				}
+				if _, err = tx.ExecContext(ctx, "DELETE FROM users WHERE id = ?", 2); err != nil {
+					_ = tx.Rollback()
+					return err
+				}
+				return tx.Commit()
+			},
+			wantName: "sqlite exec",
+			wantText: "DELETE FROM users WHERE id = ?",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var parent *ginatrace.Span
+			spans := record(t, func() {
+				var ctx context.Context
+				ctx, parent = ginatrace.Start(context.Background(), "request")
+				if err := tt.run(ctx); err != nil {
+					t.Fatal(err)
+				}
+			})
+			if len(spans) != 1 {
+				t.Fatalf("导出了 %d 个 span, 应该只有 1 个", len(spans))
+			}
+
+			span := spans[0]
+			if span.Name != tt.wantName || span.Attributes["db.query.text"] != tt.wantText || span.Attributes["db.system"] != "sqlite" {
+				t.Fatalf("span 为 %s %v", span.Name, span.Attributes)
+			}
+			if span.Parent != parent.SpanContext().SpanID || span.Kind != ginatrace.SpanKindClient {
+				t.Fatalf("span 的父 span 为 %s, 类型为 %d", span.Parent, span.Kind)
+			}
+			if hasErr := span.Status == ginatrace.StatusError; hasErr != tt.wantErr {
+				t.Fatalf("span 的状态为 %d %s", span.Status, span.StatusMessage)
+			}
+		})
+	}
+}
+
+// ctx 中没有 span 时不记录
+func TestTracedSqlxWithoutSpan(t *testing.T) {
+	db := openTestSqlx(t)
+	spans := record(t, func() {
+		if _, err := db.ExecContext(context.Background(), "INSERT INTO users (name) VALUES (?)", "a"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if len(spans) != 0 {
+		t.Fatalf("导出了 %d 个 span", len(spans))
+	}
+}
@@ -0,0 +1,114 @@
+package grpcmodule
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/soryetong/greasyx/libs/ginaerror"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain 业务错误码在 ErrorInfo 中的 Domain
+const ErrorDomain = "greasyx"
+
+// ginaerror 的错误码与 gRPC 状态码的对应关系, 未配置的错误码对应 codes.Unknown
+var (
+	codeMu  sync.RWMutex
+	codeMap = map[int64]codes.Code{
+		ginaerror.OK:                   codes.OK,
+		ginaerror.Fail:                 codes.InvalidArgument,
+		ginaerror.ServerError:          codes.Internal,
+		ginaerror.ParameterIllegal:     codes.InvalidArgument,
+		ginaerror.NoAuth:               codes.PermissionDenied,
+		ginaerror.NotData:              codes.NotFound,
+		ginaerror.HasData:              codes.AlreadyExists,
+		ginaerror.UnauthorizedToken:    codes.Unauthenticated,
+		ginaerror.NeedLogin:            codes.Unauthenticated,
+		ginaerror.RequestLimit:         codes.ResourceExhausted,
+		ginaerror.CaptchaGenerateError: codes.Internal,
+		ginaerror.CaptchaError:         codes.InvalidArgument,
+		ginaerror.LoginFail:            codes.Unauthenticated,
+		ginaerror.LoginPasswordError:   codes.Unauthenticated,
+		ginaerror.LoginNoUser:          codes.NotFound,
+		ginaerror.LoginBan:             codes.PermissionDenied,
+		ginaerror.ThirdLoginError:      codes.Unauthenticated,
+		ginaerror.UserIsset:            codes.AlreadyExists,
+	}
+)
+
+// RegisterCode 设置业务错误码对应的 gRPC 状态码, 用于自定义的错误码
+func RegisterCode(code int64, grpcCode codes.Code) {
+	codeMu.Lock()
+	defer codeMu.Unlock()
+
+	codeMap[code] = grpcCode
+}
+
+// Code 获取业务错误码对应的 gRPC 状态码
+func Code(code int64) codes.Code {
+	codeMu.RLock()
+	defer codeMu.RUnlock()
+
+	if grpcCode, ok := codeMap[code]; ok {
+		return grpcCode
+	}
+
+	return codes.Unknown
+}
+
+// Error 以业务错误码创建 gRPC 错误, 和 gina.Fail 一样, 未传入 message 时使用错误码对应的提示
+// 业务错误码保存在 ErrorInfo 中, 客户端可以通过 FromError 取出
+func Error(code int64, message ...string) error {
+	st := status.New(Code(code), ginaerror.GetErrorMessage(code, message...))
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   strconv.FormatInt(code, 10),
+		Domain:   ErrorDomain,
+		Metadata: map[string]string{"code": strconv.FormatInt(code, 10)},
+	})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// 处理函数返回的 ginaerror.Error 转为对应的 gRPC 错误, 内部原因不会返回给客户端
+func convertError(err error) error {
+	var bizErr *ginaerror.Error
+	if err == nil || !errors.As(err, &bizErr) {
+		return err
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	return Error(bizErr.Code, bizErr.Msg())
+}
+
+// FromError 从 gRPC 错误中取出业务错误码和提示, 不是通过 Error 创建的错误返回 ginaerror.ServerError
+func FromError(err error) (int64, string) {
+	if err == nil {
+		return ginaerror.OK, ginaerror.GetErrorMessage(ginaerror.OK)
+	}
+
+	var bizErr *ginaerror.Error
+	if errors.As(err, &bizErr) {
+		return bizErr.Code, bizErr.Msg()
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return ginaerror.ServerError, err.Error()
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Domain == ErrorDomain {
+			if code, err := strconv.ParseInt(info.Reason, 10, 64); err == nil {
+				return code, st.Message()
+			}
+		}
+	}
+
+	return ginaerror.ServerError, st.Message()
+}
@@ -0,0 +1,180 @@
+package grpcmodule
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/soryetong/greasyx/console"
+	"github.com/soryetong/greasyx/gina"
+	"github.com/soryetong/greasyx/ginahelper"
+	"github.com/soryetong/greasyx/libs/ginagrace"
+	"github.com/soryetong/greasyx/modules/httpmodule"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// RegisterFunc 向 grpc.Server 注册业务服务, 如 pb.RegisterUserServer(srv, &userServer{})
+type RegisterFunc func(srv *grpc.Server)
+
+// IGrpc 与 httpmodule.IHttp 的用法一致, 在服务的 OnStart 中调用 Init 和 Start, 在 OnStop 中调用 Stop
+// 默认注册健康检查和反射服务, 所有服务的健康状态随服务的启动和停止变化
+type IGrpc struct {
+	*grpc.Server
+
+	name       string
+	caller     any
+	listenAddr string
+	timeout    time.Duration
+	register   RegisterFunc
+	opts       []grpc.ServerOption
+	health     *health.Server
+	listener   net.Listener
+
+	hooks    *httpmodule.CallbackMap
+	exit     chan error
+	stopped  chan error
+	stopOnce sync.Once
+}
+
+// Init 初始化服务, timeout 为停机时等待处理中的请求完成的最长时间, 如 5*time.Second, 为 0 时只受 App.ShutdownTimeout 的限制
+// 小于 1ms 的值按秒处理, 兼容以前传入 5 的写法
+// opts 为 grpc.Server 的配置, 拦截器通过 grpc.ChainUnaryInterceptor 和 grpc.ChainStreamInterceptor 设置
+func (self *IGrpc) Init(caller interface{}, addr string, timeout time.Duration, register RegisterFunc, opts ...grpc.ServerOption) {
+	self.name = ginahelper.GetCallerName(caller)
+	self.caller = caller
+	// 服务未实现 gina.IStopper 时, 服务管理器停机时调用 Stop
+	gina.AttachStopper(caller, self.Stop)
+	self.exit = make(chan error, 1)
+	self.stopped = make(chan error, 1)
+	self.listenAddr = addr
+	self.register = register
+	self.opts = opts
+
+	self.timeout = httpmodule.NormalizeShutdownTimeout(self.name, timeout)
+}
+
+// Name 服务名称, 为 Init 时传入的 caller 的类型名
+func (self *IGrpc) Name() string {
+	return self.name
+}
+
+// Addr 实际监听的地址, 启动前为 Init 时传入的地址
+func (self *IGrpc) Addr() string {
+	if self.listener != nil {
+		return self.listener.Addr().String()
+	}
+
+	return self.listenAddr
+}
+
+// Health 健康检查服务, 可以通过 SetServingStatus 单独设置某个服务的状态
+func (self *IGrpc) Health() *health.Server {
+	return self.health
+}
+
+// OnStop 设置服务启动和停机时的回调函数, 各阶段的执行时机见 httpmodule.Phase
+func (self *IGrpc) OnStop(data *httpmodule.CallbackMap) {
+	self.hooks = data
+}
+
+// OnInit 创建 grpc.Server 并注册业务服务、健康检查和反射服务
+func (self *IGrpc) OnInit() {
+	self.Server = grpc.NewServer(self.opts...)
+	if self.register != nil {
+		self.register(self.Server)
+	}
+
+	self.health = health.NewServer()
+	grpc_health_v1.RegisterHealthServer(self.Server, self.health)
+	reflection.Register(self.Server)
+}
+
+// Start 在 Init 指定的地址上启动 gRPC 服务
+func (self *IGrpc) Start() error {
+	if self.listenAddr == "" {
+		return errors.New("未指定监听地址")
+	}
+
+	if err := self.hooks.Run(context.Background(), httpmodule.PhaseBeforeStart); err != nil {
+		return err
+	}
+	self.OnInit()
+	ln, err := ginagrace.Listen("tcp", self.listenAddr)
+	if err != nil {
+		return err
+	}
+	self.listener = ln
+
+	go func() {
+		if err := self.Serve(ln); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			console.Echo.Errorf("❌  错误: 服务启动异常 %s", err)
+			select {
+			case self.exit <- err:
+			default:
+			}
+		}
+	}()
+	self.health.Resume()
+	console.Echo.Infof("✅ 提示: 服务 %s 启动成功，地址为: grpc://%s\n", self.name, ln.Addr())
+	gina.Started(self.caller)
+
+	return self.running()
+}
+
+// Stop 优雅停止服务, 健康状态先变为 NOT_SERVING, 再等待处理中的请求完成, 超时后强制关闭所有连接
+// 停止监听前执行 PhaseBeforeShutdown 阶段的回调, 请求全部完成后执行 PhaseAfterDrain 阶段的回调
+func (self *IGrpc) Stop(ctx context.Context) (err error) {
+	if self.Server == nil {
+		return nil
+	}
+
+	self.stopOnce.Do(func() {
+		self.health.Shutdown()
+		hookErr := self.hooks.Run(ctx, httpmodule.PhaseBeforeShutdown)
+		shutdownCtx := ctx
+		if self.timeout > 0 {
+			var cancel context.CancelFunc
+			shutdownCtx, cancel = context.WithTimeout(ctx, self.timeout)
+			defer cancel()
+		}
+
+		done := make(chan struct{})
+		go func() {
+			self.GracefulStop()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-shutdownCtx.Done():
+			// 流式请求可能一直不结束, 超时后强制关闭
+			self.Server.Stop()
+			<-done
+			err = shutdownCtx.Err()
+			console.Echo.Warnf("⚠️ 警告: 服务停机失败: %s\n", err)
+		}
+		err = errors.Join(err, hookErr, self.hooks.Run(ctx, httpmodule.PhaseAfterDrain))
+		self.stopped <- err
+	})
+
+	return
+}
+
+// 阻塞直到服务异常退出或者通过 Stop 停止
+func (self *IGrpc) running() error {
+	select {
+	case err := <-self.exit:
+		return err
+	case err := <-self.stopped:
+		if err != nil {
+			return err
+		}
+
+		console.Echo.Infof("✅ 提示: 服务 %s 已成功关闭\n", self.name)
+		return nil
+	}
+}
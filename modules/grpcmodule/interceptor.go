@@ -0,0 +1,340 @@
+package grpcmodule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/soryetong/greasyx/console"
+	"github.com/soryetong/greasyx/gina"
+	"github.com/soryetong/greasyx/ginahelper"
+	"github.com/soryetong/greasyx/libs/ginaauth"
+	"github.com/soryetong/greasyx/libs/ginaerror"
+	"github.com/soryetong/greasyx/libs/ginamiddleware"
+	"github.com/soryetong/greasyx/libs/ginasrv"
+	"github.com/soryetong/greasyx/libs/ginatrace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// CasbinAction gRPC 请求在 Casbin 中的 act, 策略的 obj 为完整的方法名, 如 p, 1, /user.User/Detail, GRPC
+const CasbinAction = "GRPC"
+
+// 健康检查和反射服务不需要鉴权、限流和记录日志
+func isInternal(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/grpc.health.v1.") || strings.HasPrefix(fullMethod, "/grpc.reflection.")
+}
+
+// 用于在流式请求中替换 context
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (self *serverStream) Context() context.Context {
+	return self.ctx
+}
+
+func wrapStream(ss grpc.ServerStream, ctx context.Context) grpc.ServerStream {
+	if ctx == ss.Context() {
+		return ss
+	}
+
+	return &serverStream{ServerStream: ss, ctx: ctx}
+}
+
+// 客户端的 IP, 不包含端口
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
+
+func metadataValue(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+// UnaryBegin 与 ginamiddleware.Begin 一致, 为每个请求创建 span, 并把 trace_id 保存在 ctx 中用于日志关联
+// 同时把返回的 ginaerror.Error 转为对应的 gRPC 错误, 需要放在第一个
+func UnaryBegin() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, span := startSpan(ctx, info.FullMethod)
+		defer span.End()
+
+		resp, err := handler(ctx, req)
+		err = convertError(err)
+		endSpan(span, err)
+
+		return resp, err
+	}
+}
+
+// StreamBegin 同 UnaryBegin, 用于流式请求
+func StreamBegin() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := startSpan(ss.Context(), info.FullMethod)
+		defer span.End()
+
+		err := convertError(handler(srv, wrapStream(ss, ctx)))
+		endSpan(span, err)
+
+		return err
+	}
+}
+
+func startSpan(ctx context.Context, fullMethod string) (context.Context, *ginatrace.Span) {
+	service, method, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	opts := []ginatrace.StartOption{
+		ginatrace.WithKind(ginatrace.SpanKindServer),
+		ginatrace.WithAttributes(map[string]any{
+			"rpc.system":     "grpc",
+			"rpc.service":    service,
+			"rpc.method":     method,
+			"client.address": clientIP(ctx),
+		}),
+	}
+
+	// metadata 的 key 都是小写, 转为 http.Header 后解析
+	header := make(http.Header)
+	header.Set(ginatrace.HeaderTraceparent, metadataValue(ctx, ginatrace.HeaderTraceparent))
+	header.Set(ginatrace.HeaderTracestate, metadataValue(ctx, ginatrace.HeaderTracestate))
+	if remote, ok := ginatrace.Extract(header); ok {
+		opts = append(opts, ginatrace.WithRemoteParent(remote))
+	}
+
+	ctx, span := ginatrace.Start(ctx, fullMethod, opts...)
+	ctx = context.WithValue(ctx, "trace_id", span.TraceID())
+	ctx = context.WithValue(ctx, "source", "GrpcRequest")
+
+	return ctx, span
+}
+
+func endSpan(span *ginatrace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttr("rpc.grpc.status_code", int(code))
+	if err != nil {
+		span.RecordError(err)
+	}
+}
+
+// UnaryRecovery 捕获 panic 并返回 ginaerror.ServerError, 避免整个服务退出, 放在 UnaryBegin 之后以捕获其他拦截器中的 panic
+func UnaryRecovery() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				gina.Log.WithCtx(ctx).Error("[Grpc.Recovery] 请求发生panic", zap.String("method", info.FullMethod),
+					zap.Any("panic", r), zap.String("stack", string(debug.Stack())))
+				err = Error(ginaerror.ServerError)
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecovery 同 UnaryRecovery, 用于流式请求
+func StreamRecovery() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				gina.Log.WithCtx(ss.Context()).Error("[Grpc.Recovery] 请求发生panic", zap.String("method", info.FullMethod),
+					zap.Any("panic", r), zap.String("stack", string(debug.Stack())))
+				err = Error(ginaerror.ServerError)
+			}
+		}()
+
+		return handler(srv, ss)
+	}
+}
+
+// UnaryJwt 与 ginamiddleware.Jwt 一致, 从 metadata 的 authorization 中读取 Bearer Token
+// 校验通过后 claims 保存在 ctx 中, 可以通过 ginaauth.GetTokenData 读取, skip 中的方法不需要登录
+func UnaryJwt(skip ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, info.FullMethod, skip)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamJwt 同 UnaryJwt, 用于流式请求
+func StreamJwt(skip ...string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), info.FullMethod, skip)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, wrapStream(ss, ctx))
+	}
+}
+
+func authenticate(ctx context.Context, fullMethod string, skip []string) (context.Context, error) {
+	if isInternal(fullMethod) || slices.Contains(skip, fullMethod) {
+		return ctx, nil
+	}
+
+	tokenString := metadataValue(ctx, "authorization")
+	if tokenString == "" || !strings.HasPrefix(tokenString, "Bearer ") {
+		return ctx, Error(ginaerror.NeedLogin)
+	}
+
+	claims, err := ginaauth.ParseJwtToken(tokenString[7:])
+	if err != nil {
+		return ctx, Error(ginaerror.NeedLogin)
+	}
+
+	return context.WithValue(ctx, "claims", claims), nil
+}
+
+// UnaryCasbin 与 ginamiddleware.Casbin 一致, 需要放在 UnaryJwt 之后, 以 role_id、完整的方法名和 CasbinAction 校验权限
+func UnaryCasbin() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := enforce(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamCasbin 同 UnaryCasbin, 用于流式请求
+func StreamCasbin() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := enforce(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+func enforce(ctx context.Context, fullMethod string) error {
+	if isInternal(fullMethod) {
+		return nil
+	}
+
+	roleId := ginaauth.GetTokenData[int64](ctx, "role_id")
+	if roleId == 0 {
+		console.Echo.Info("ℹ️ 提示: 无法使用 `Casbin` 权限校验, 请确保 `Token` 中包含了字段 `role_id`")
+		return nil
+	}
+
+	success, _ := gina.Casbin.Enforce(ginahelper.Int64ToString(roleId), fullMethod, CasbinAction)
+	if !success {
+		return Error(ginaerror.NoAuth)
+	}
+
+	return nil
+}
+
+// UnaryLimiter 与 ginamiddleware.Limiter 一致, 按接口限流时规则的 Route 为完整的方法名, 如 /user.User/Detail
+func UnaryLimiter(limiterStore *ginasrv.LimiterStore) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !allow(ctx, limiterStore, info.FullMethod) {
+			return nil, Error(ginaerror.RequestLimit)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamLimiter 同 UnaryLimiter, 只在建立流时判断一次
+func StreamLimiter(limiterStore *ginasrv.LimiterStore) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !allow(ss.Context(), limiterStore, info.FullMethod) {
+			return Error(ginaerror.RequestLimit)
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+func allow(ctx context.Context, limiterStore *ginasrv.LimiterStore, fullMethod string) bool {
+	if isInternal(fullMethod) {
+		return true
+	}
+
+	return limiterStore.AllowRequest(clientIP(ctx), ginaauth.GetTokenData[int64](ctx, "id"), fullMethod)
+}
+
+// UnaryRequestLog 与 ginamiddleware.RequestLog 一致, 记录请求和响应, StatusCode 为业务错误码
+func UnaryRequestLog() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if isInternal(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		logData := newRequestLog(ctx, info.FullMethod)
+		request, _ := json.Marshal(req)
+		logData.Request = string(request)
+		startTime := time.Now()
+
+		resp, err := handler(ctx, req)
+
+		if err == nil {
+			response, _ := json.Marshal(resp)
+			logData.Response = string(response)
+		}
+		writeRequestLog(ctx, logData, startTime, err)
+
+		return resp, err
+	}
+}
+
+// StreamRequestLog 同 UnaryRequestLog, 流式请求只记录耗时和结果, 不记录消息内容
+func StreamRequestLog() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if isInternal(info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		logData := newRequestLog(ss.Context(), info.FullMethod)
+		startTime := time.Now()
+		err := handler(srv, ss)
+		writeRequestLog(ss.Context(), logData, startTime, err)
+
+		return err
+	}
+}
+
+func newRequestLog(ctx context.Context, fullMethod string) *ginamiddleware.RequestLogData {
+	userAgent := metadataValue(ctx, "user-agent")
+	return &ginamiddleware.RequestLogData{
+		Method:   CasbinAction,
+		Path:     fullMethod,
+		UserId:   ginaauth.GetTokenData[int64](ctx, "id"),
+		Username: ginaauth.GetTokenData[string](ctx, "username"),
+		Platform: userAgent,
+		Ip:       clientIP(ctx),
+	}
+}
+
+func writeRequestLog(ctx context.Context, logData *ginamiddleware.RequestLogData, startTime time.Time, err error) {
+	logData.Elapsed = fmt.Sprintf("%.2f", time.Since(startTime).Seconds()*1000)
+	logData.StatusCode, logData.Msg = FromError(err)
+	gina.Log.WithCtx(ctx).Info("[GrpcRequestLog]请求响应日志", zap.Any("logData", logData), zap.String("grpcCode", status.Code(err).String()))
+}
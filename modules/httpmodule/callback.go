@@ -1,36 +1,218 @@
 package httpmodule
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+
 	"github.com/soryetong/greasyx/console"
 )
 
-// 回调函数, 严格按照注册的顺序执行
+// Phase 回调函数执行的阶段
+type Phase int
+
+const (
+	PhaseBeforeStart    Phase = iota // 开始监听之前, 如预热缓存, 返回错误时服务不会启动
+	PhaseBeforeShutdown              // 停止接收新请求之前, 此时还在正常处理请求, 如从注册中心注销
+	PhaseAfterDrain                  // 处理中的请求全部完成之后, 如刷新缓冲区、关闭客户端
+)
+
+func (self Phase) String() string {
+	switch self {
+	case PhaseBeforeStart:
+		return "before-start"
+	case PhaseBeforeShutdown:
+		return "before-shutdown"
+	case PhaseAfterDrain:
+		return "after-drain"
+	}
+
+	return fmt.Sprintf("phase(%d)", int(self))
+}
+
+// HookFunc 回调函数, ctx 在回调的超时时间或者停机的截止时间到期后取消
+type HookFunc func(ctx context.Context) error
+
+// HookOption 回调函数的可选配置
+type HookOption func(*hook)
+
+// WithPriority 优先级, 同一阶段中优先级高的先执行, 相同时按注册顺序执行, 默认为 0
+func WithPriority(priority int) HookOption {
+	return func(h *hook) {
+		h.priority = priority
+	}
+}
+
+// WithTimeout 单个回调的超时时间, 默认只受停机截止时间的限制
+func WithTimeout(timeout time.Duration) HookOption {
+	return func(h *hook) {
+		h.timeout = timeout
+	}
+}
+
+// WithGroup 同一阶段中同一组的回调并行执行, 整组在组内第一个回调的位置执行
+func WithGroup(group string) HookOption {
+	return func(h *hook) {
+		h.group = group
+	}
+}
+
+type hook struct {
+	name     string
+	phase    Phase
+	fn       HookFunc
+	priority int
+	timeout  time.Duration
+	group    string
+}
+
+// 回调函数, 按阶段和优先级执行
 type CallbackMap struct {
-	funcMap  map[string]func()
-	nameList []string
+	mu    sync.Mutex
+	hooks []*hook
+}
+
+// NewCallbackMap 创建回调函数集合, 通过 IHttp.OnStop 设置后在服务启动和停机的各个阶段执行
+func NewCallbackMap() *CallbackMap {
+	return &CallbackMap{}
 }
 
-// 服务停机时的回调函数, 严格按照Append的的顺序执行, 先进先出, 同名会被覆盖
+// NewStopCallbackMap 同 NewCallbackMap, 保留用于兼容
+// 回调不再只在停机后按 Append 的顺序执行, 而是分为 PhaseBeforeStart、PhaseBeforeShutdown、PhaseAfterDrain 三个阶段,
+// 同一阶段中按优先级从高到低执行, 优先级相同时按注册顺序, 同一组的回调并行执行; 通过 Append 注册的回调属于 PhaseAfterDrain
 func NewStopCallbackMap() *CallbackMap {
-	return &CallbackMap{
-		funcMap:  make(map[string]func()),
-		nameList: make([]string, 0),
+	return NewCallbackMap()
+}
+
+// Add 注册回调函数, 同一阶段同名会被覆盖
+func (self *CallbackMap) Add(name string, phase Phase, fn HookFunc, opts ...HookOption) {
+	h := &hook{name: name, phase: phase, fn: fn}
+	for _, opt := range opts {
+		opt(h)
 	}
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	for i, exists := range self.hooks {
+		if exists.name == name && exists.phase == phase {
+			self.hooks[i] = h
+			return
+		}
+	}
+	self.hooks = append(self.hooks, h)
 }
 
+// Append 注册处理中的请求全部完成之后执行的回调函数, 同 Add(funcName, PhaseAfterDrain, ...)
 func (self *CallbackMap) Append(funcName string, value func()) {
-	if _, exists := self.funcMap[funcName]; !exists {
-		self.nameList = append(self.nameList, funcName)
-	}
-	self.funcMap[funcName] = value
+	self.Add(funcName, PhaseAfterDrain, func(ctx context.Context) error {
+		value()
+		return nil
+	})
 }
 
+// Foreach 执行 PhaseAfterDrain 阶段的回调函数
 func (self *CallbackMap) Foreach() {
-	if self == nil || len(self.nameList) == 0 {
-		return
+	_ = self.Run(context.Background(), PhaseAfterDrain)
+}
+
+// Run 执行指定阶段的回调函数, 单个回调失败不影响其他回调, 返回所有失败的错误, 并统一记录日志
+func (self *CallbackMap) Run(ctx context.Context, phase Phase) error {
+	if self == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, step := range self.steps(phase) {
+		errs = append(errs, runStep(ctx, step)...)
 	}
-	for _, funcName := range self.nameList {
-		console.Echo.Infof("ℹ️ 提示: 即将执行退出的回调函数: %s\n", funcName)
-		self.funcMap[funcName]()
+	if err := errors.Join(errs...); err != nil {
+		console.Echo.Errorf("❌ 错误: %s 阶段有 %d 个回调函数执行失败:\n%s", phase, len(errs), err)
+		return err
+	}
+
+	return nil
+}
+
+// 按优先级排序, 同一组的回调合并为一步
+func (self *CallbackMap) steps(phase Phase) [][]*hook {
+	self.mu.Lock()
+	var hooks []*hook
+	for _, h := range self.hooks {
+		if h.phase == phase {
+			hooks = append(hooks, h)
+		}
+	}
+	self.mu.Unlock()
+
+	slices.SortStableFunc(hooks, func(a, b *hook) int {
+		return b.priority - a.priority
+	})
+
+	var steps [][]*hook
+	groups := make(map[string]int)
+	for _, h := range hooks {
+		if h.group == "" {
+			steps = append(steps, []*hook{h})
+			continue
+		}
+		if i, ok := groups[h.group]; ok {
+			steps[i] = append(steps[i], h)
+			continue
+		}
+		groups[h.group] = len(steps)
+		steps = append(steps, []*hook{h})
+	}
+
+	return steps
+}
+
+func runStep(ctx context.Context, step []*hook) []error {
+	errs := make([]error, len(step))
+	var wg sync.WaitGroup
+	for i, h := range step {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = runHook(ctx, h)
+		}()
+	}
+	wg.Wait()
+
+	return slices.DeleteFunc(errs, func(err error) bool {
+		return err == nil
+	})
+}
+
+// 执行单个回调, 超时后不再等待, panic 作为错误返回
+func runHook(ctx context.Context, h *hook) error {
+	console.Echo.Infof("ℹ️ 提示: 即将执行 %s 阶段的回调函数: %s\n", h.phase, h.name)
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v, stack=%s", r, debug.Stack())
+			}
+		}()
+		done <- h.fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("%s: %w", h.name, err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", h.name, ctx.Err())
 	}
 }
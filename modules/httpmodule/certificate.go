@@ -0,0 +1,324 @@
+package httpmodule
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/soryetong/greasyx/console"
+	"github.com/soryetong/greasyx/ginahelper"
+)
+
+const (
+	// 证书文件变化后等待的时间, 避免证书和私钥分两次写入时读到不匹配的文件
+	certDebounce = 500 * time.Millisecond
+	// 证书在到期前多久开始提醒
+	certExpiryWarning = 30 * 24 * time.Hour
+	// 检查证书有效期的间隔
+	certCheckInterval = 24 * time.Hour
+)
+
+// CertPair 证书和私钥文件
+type CertPair struct {
+	CertFile string
+	KeyFile  string
+}
+
+// 一次加载的所有证书, 整体替换, 保证请求不会读到只更新了一半的证书
+type certSet struct {
+	certs      []*tls.Certificate
+	names      map[string]*tls.Certificate // 证书中的域名, 小写
+	clientCA   *x509.CertPool
+	clientAuth tls.ClientAuthType
+}
+
+// CertProvider 证书提供者, 监听证书文件的变化并自动重新加载, 无需重启服务
+// 支持按 SNI 选择多个证书, 以及通过客户端 CA 校验客户端证书 (mTLS)
+type CertProvider struct {
+	pairs      []CertPair
+	clientCA   string
+	clientAuth tls.ClientAuthType
+
+	current atomic.Pointer[certSet]
+	mu      sync.Mutex
+	watcher *fsnotify.Watcher
+	timer   *time.Timer
+	done    chan struct{}
+}
+
+// NewCertProvider 加载证书, 第一个证书为客户端未指定 SNI 或者没有匹配的域名时使用的默认证书
+func NewCertProvider(pairs ...CertPair) (*CertProvider, error) {
+	if len(pairs) == 0 {
+		return nil, errors.New("至少需要一个证书")
+	}
+
+	provider := &CertProvider{pairs: pairs}
+	if err := provider.Reload(); err != nil {
+		return nil, err
+	}
+	provider.checkExpiry()
+
+	return provider, nil
+}
+
+// WithClientCA 开启 mTLS, 使用 caFile 中的 CA 校验客户端证书, optional 为 true 时只校验客户端提供的证书
+// 加载失败时不开启, 继续使用原来的配置
+func (self *CertProvider) WithClientCA(caFile string, optional bool) error {
+	clientAuth := tls.RequireAndVerifyClientCert
+	if optional {
+		clientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	set, err := self.load(caFile, clientAuth)
+	if err != nil {
+		return err
+	}
+	self.clientCA = caFile
+	self.clientAuth = clientAuth
+	self.current.Store(set)
+
+	return nil
+}
+
+// TLSConfig 生成服务端的 tls.Config, 证书和客户端 CA 都从 CertProvider 中实时获取
+func (self *CertProvider) TLSConfig() *tls.Config {
+	base := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		NextProtos:     []string{"h2", "http/1.1"},
+		GetCertificate: self.GetCertificate,
+	}
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		set := self.current.Load()
+		if set.clientCA == nil {
+			return nil, nil
+		}
+
+		config := base.Clone()
+		config.GetConfigForClient = nil
+		config.ClientCAs = set.clientCA
+		config.ClientAuth = set.clientAuth
+		return config, nil
+	}
+
+	return base
+}
+
+// GetCertificate 按 SNI 选择证书, 支持 *.example.com 形式的泛域名证书
+func (self *CertProvider) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	set := self.current.Load()
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+	if cert, ok := set.names[name]; ok {
+		return cert, nil
+	}
+	if _, rest, ok := strings.Cut(name, "."); ok {
+		if cert, ok := set.names["*."+rest]; ok {
+			return cert, nil
+		}
+	}
+
+	return set.certs[0], nil
+}
+
+// Reload 重新加载所有证书, 任一证书加载失败时继续使用原来的证书
+func (self *CertProvider) Reload() error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	return self.reload()
+}
+
+// 调用方需要持有 self.mu
+func (self *CertProvider) reload() error {
+	set, err := self.load(self.clientCA, self.clientAuth)
+	if err != nil {
+		return err
+	}
+	self.current.Store(set)
+
+	return nil
+}
+
+// 加载所有证书和客户端 CA, 调用方需要持有 self.mu
+func (self *CertProvider) load(caFile string, clientAuth tls.ClientAuthType) (*certSet, error) {
+	set := &certSet{names: make(map[string]*tls.Certificate), clientAuth: clientAuth}
+	for _, pair := range self.pairs {
+		cert, err := tls.LoadX509KeyPair(pair.CertFile, pair.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载证书 %s 失败: %w", pair.CertFile, err)
+		}
+		if cert.Leaf == nil {
+			if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+				return nil, fmt.Errorf("解析证书 %s 失败: %w", pair.CertFile, err)
+			}
+		}
+
+		set.certs = append(set.certs, &cert)
+		names := cert.Leaf.DNSNames
+		if len(names) == 0 && cert.Leaf.Subject.CommonName != "" {
+			names = []string{cert.Leaf.Subject.CommonName}
+		}
+		for _, name := range names {
+			name = strings.ToLower(name)
+			if _, exists := set.names[name]; !exists {
+				set.names[name] = &cert
+			}
+		}
+	}
+
+	if caFile != "" {
+		data, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取客户端 CA %s 失败: %w", caFile, err)
+		}
+		set.clientCA = x509.NewCertPool()
+		if !set.clientCA.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("客户端 CA %s 中没有有效的证书", caFile)
+		}
+	}
+
+	return set, nil
+}
+
+// Watch 监听证书文件所在的目录, 兼容 Kubernetes 通过替换软链接更新 Secret 的方式, 多次调用只会监听一次
+func (self *CertProvider) Watch() error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.watcher != nil {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	files := []string{self.clientCA}
+	for _, pair := range self.pairs {
+		files = append(files, pair.CertFile, pair.KeyFile)
+	}
+	dirs := make(map[string]bool)
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		dir := filepath.Dir(file)
+		if dirs[dir] {
+			continue
+		}
+		dirs[dir] = true
+		if err = watcher.Add(dir); err != nil {
+			_ = watcher.Close()
+			return fmt.Errorf("监听证书目录 %s 失败: %w", dir, err)
+		}
+	}
+
+	self.watcher = watcher
+	self.done = make(chan struct{})
+	ginahelper.SafeGo(func() {
+		self.watch(watcher, self.done)
+	})
+
+	return nil
+}
+
+func (self *CertProvider) watch(watcher *fsnotify.Watcher, done chan struct{}) {
+	ticker := time.NewTicker(certCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			self.checkExpiry()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			console.Echo.Errorf("❌ 错误: 监听证书文件失败: %s", err)
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			self.scheduleReload(done)
+		}
+	}
+}
+
+// 文件变化后等待 certDebounce 再重新加载, 期间的多次变化只加载一次
+// Close 与文件事件同时发生时, 定时器可能在 Close 之后才创建或者执行, 执行时需要检查是否已经关闭
+func (self *CertProvider) scheduleReload(done chan struct{}) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.timer != nil {
+		self.timer.Stop()
+	}
+	self.timer = time.AfterFunc(certDebounce, func() {
+		self.mu.Lock()
+		if self.closed(done) {
+			self.mu.Unlock()
+			return
+		}
+		err := self.reload()
+		self.mu.Unlock()
+		if err != nil {
+			console.Echo.Errorf("❌ 错误: 证书热更新失败, 继续使用原来的证书: %s", err)
+			return
+		}
+		console.Echo.Infof("✅ 提示: 证书热更新成功\n")
+		self.checkExpiry()
+	})
+}
+
+// Close 停止监听证书文件, 文件变化后还未执行的重新加载不会再执行
+func (self *CertProvider) Close() error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.watcher == nil {
+		return nil
+	}
+
+	if self.timer != nil {
+		self.timer.Stop()
+	}
+	close(self.done)
+	err := self.watcher.Close()
+	self.watcher = nil
+
+	return err
+}
+
+// done 为 Watch 时创建的通道, Close 后关闭
+func (self *CertProvider) closed(done chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+// 证书即将到期或者已经到期时提醒
+func (self *CertProvider) checkExpiry() {
+	set := self.current.Load()
+	for i, cert := range set.certs {
+		remain := time.Until(cert.Leaf.NotAfter)
+		switch {
+		case remain <= 0:
+			console.Echo.Errorf("❌ 错误: 证书 %s 已于 %s 过期", self.pairs[i].CertFile, cert.Leaf.NotAfter.Format(time.DateTime))
+		case remain < certExpiryWarning:
+			console.Echo.Warnf("⚠️ 警告: 证书 %s 将于 %s 过期, 剩余 %d 天\n",
+				self.pairs[i].CertFile, cert.Leaf.NotAfter.Format(time.DateTime), int(remain.Hours()/24))
+		}
+	}
+}
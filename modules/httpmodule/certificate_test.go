@@ -0,0 +1,246 @@
+package httpmodule
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 生成自签名证书写入 dir, 返回证书和私钥文件, cn 用于区分证书
+func writeCert(t *testing.T, dir, cn string, names ...string) CertPair {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(365 * 24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pair := CertPair{CertFile: filepath.Join(dir, cn+".crt"), KeyFile: filepath.Join(dir, cn+".key")}
+	writePem(t, pair.CertFile, "CERTIFICATE", der)
+	writePem(t, pair.KeyFile, "EC PRIVATE KEY", keyDer)
+
+	return pair
+}
+
+func writePem(t *testing.T, path, typ string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func commonName(t *testing.T, provider *CertProvider, serverName string) string {
+	t.Helper()
+	cert, err := provider.GetCertificate(&tls.ClientHelloInfo{ServerName: serverName})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert.Leaf.Subject.CommonName
+}
+
+func TestGetCertificate(t *testing.T) {
+	dir := t.TempDir()
+	provider, err := NewCertProvider(
+		writeCert(t, dir, "default", "example.com"),
+		writeCert(t, dir, "api", "api.example.com"),
+		writeCert(t, dir, "wildcard", "*.example.org"),
+		writeCert(t, dir, "duplicate", "api.example.com"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		serverName string
+		want       string
+	}{
+		{serverName: "example.com", want: "default"},
+		{serverName: "api.example.com", want: "api"},
+		{serverName: "API.Example.com.", want: "api"},
+		{serverName: "www.example.org", want: "wildcard"},
+		{serverName: "a.b.example.org", want: "default"},
+		{serverName: "unknown.com", want: "default"},
+		{serverName: "", want: "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.serverName, func(t *testing.T) {
+			if got := commonName(t, provider, tt.serverName); got != tt.want {
+				t.Fatalf("选择的证书为 %s, 应该为 %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// 重新加载失败时继续使用原来的证书
+func TestReloadKeepsOldCerts(t *testing.T) {
+	dir := t.TempDir()
+	pair := writeCert(t, dir, "old", "example.com")
+	provider, err := NewCertProvider(pair)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.WriteFile(pair.CertFile, []byte("broken"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = provider.Reload(); err == nil {
+		t.Fatal("证书无效时应该返回错误")
+	}
+	if got := commonName(t, provider, "example.com"); got != "old" {
+		t.Fatalf("加载失败后使用的证书为 %s", got)
+	}
+}
+
+// 客户端 CA 加载成功后才开启 mTLS, 失败时继续使用原来的配置
+func TestWithClientCA(t *testing.T) {
+	dir := t.TempDir()
+	provider, err := NewCertProvider(writeCert(t, dir, "server", "example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := provider.TLSConfig()
+	clientConfig := func() *tls.Config {
+		t.Helper()
+		c, err := config.GetConfigForClient(&tls.ClientHelloInfo{ServerName: "example.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	broken := filepath.Join(dir, "broken.crt")
+	if err = os.WriteFile(broken, []byte("broken"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = provider.WithClientCA(broken, false); err == nil {
+		t.Fatal("客户端 CA 无效时应该返回错误")
+	}
+	if c := clientConfig(); c != nil {
+		t.Fatalf("加载失败后不应该开启 mTLS, 客户端校验方式为 %s", c.ClientAuth)
+	}
+	if err = provider.Reload(); err != nil {
+		t.Fatalf("加载失败的客户端 CA 不应该影响之后的重新加载: %s", err)
+	}
+
+	ca := writeCert(t, dir, "ca")
+	if err = provider.WithClientCA(ca.CertFile, true); err != nil {
+		t.Fatal(err)
+	}
+	if c := clientConfig(); c == nil || c.ClientCAs == nil || c.ClientAuth != tls.VerifyClientCertIfGiven {
+		t.Fatalf("开启 mTLS 后的配置为 %+v", c)
+	}
+}
+
+// 使用新生成的证书覆盖 pair 中的文件
+func replaceCert(t *testing.T, cn string, pair CertPair) {
+	t.Helper()
+	next := writeCert(t, t.TempDir(), cn, "example.com")
+	for src, dst := range map[string]string{next.CertFile: pair.CertFile, next.KeyFile: pair.KeyFile} {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(dst, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// 证书文件变化后自动重新加载
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	pair := writeCert(t, dir, "old", "example.com")
+	provider, err := NewCertProvider(pair)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = provider.Watch(); err != nil {
+		t.Fatal(err)
+	}
+	defer provider.Close()
+
+	replaceCert(t, "new", pair)
+	deadline := time.Now().Add(5 * time.Second)
+	for commonName(t, provider, "example.com") != "new" {
+		if time.Now().After(deadline) {
+			t.Fatal("证书文件变化后没有重新加载")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// Close 之后才处理的文件事件不再重新加载
+func TestCloseCancelsPendingReload(t *testing.T) {
+	dir := t.TempDir()
+	pair := writeCert(t, dir, "old", "example.com")
+	provider, err := NewCertProvider(pair)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = provider.Watch(); err != nil {
+		t.Fatal(err)
+	}
+	done := provider.done
+	if err = provider.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	replaceCert(t, "new", pair)
+	provider.scheduleReload(done)
+	time.Sleep(2 * certDebounce)
+	if got := commonName(t, provider, "example.com"); got != "old" {
+		t.Fatalf("Close 之后仍然重新加载了证书 %s", got)
+	}
+}
+
+// 停止服务时只关闭 IHttp 自己创建的证书提供者
+func TestCloseCertsOwnership(t *testing.T) {
+	dir := t.TempDir()
+	own := writeCert(t, dir, "own", "own.example.com")
+	custom, err := NewCertProvider(writeCert(t, dir, "custom", "custom.example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer custom.Close()
+
+	srv := &IHttp{listeners: []Listener{
+		HTTPS("127.0.0.1:0", own.CertFile, own.KeyFile),
+		HTTPSWithCerts("127.0.0.1:0", custom),
+	}}
+	if err = srv.initCerts(); err != nil {
+		t.Fatal(err)
+	}
+	created := srv.listeners[0].Certs
+	srv.closeCerts()
+
+	if created.watcher != nil {
+		t.Fatal("IHttp 创建的证书提供者应该被关闭")
+	}
+	if custom.watcher == nil {
+		t.Fatal("通过 HTTPSWithCerts 传入的证书提供者不应该被关闭")
+	}
+}
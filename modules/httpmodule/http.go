@@ -2,103 +2,300 @@ package httpmodule
 
 import (
 	"context"
+	"errors"
+	"net"
 	"net/http"
-	"os"
-	"os/signal"
-	"syscall"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/soryetong/greasyx/console"
+	"github.com/soryetong/greasyx/gina"
 	"github.com/soryetong/greasyx/ginahelper"
 )
 
+// 已启动的服务, 用于查看所有服务的监听地址和路由
+var (
+	instancesMu sync.Mutex
+	instances   []*IHttp
+)
+
+// Instances 获取当前进程中正在运行的服务
+func Instances() []*IHttp {
+	instancesMu.Lock()
+	defer instancesMu.Unlock()
+
+	return slices.Clone(instances)
+}
+
 type IHttp struct {
 	*gin.Engine
 
 	name       string
+	caller     any
 	listenAddr string
-	timeout    time.Duration
-	srv        *http.Server
-	tls        bool
+	config     ServerConfig
+	listeners  []Listener
 
-	stopCallback *CallbackMap
-	exit         chan error
+	mu      sync.Mutex // 保护 servers, 启动时可能同时收到停机信号
+	servers []*http.Server
+
+	hooks    *CallbackMap
+	exit     chan error
+	stopped  chan error
+	stopOnce sync.Once
 }
 
-func (self *IHttp) Init(caller interface{}, addr string, timeout time.Duration, engine *gin.Engine) {
+// Init 初始化服务, timeout 为停机时等待处理中的请求完成的最长时间, 如 5*time.Second, 为 0 时只受 App.ShutdownTimeout 的限制
+// 小于 1ms 的值按秒处理, 兼容以前传入 5 的写法
+// http.Server 的其他参数从 App.Server 中读取, 也可以通过 opts 设置
+func (self *IHttp) Init(caller interface{}, addr string, timeout time.Duration, engine *gin.Engine, opts ...ServerOption) {
 	self.name = ginahelper.GetCallerName(caller)
-	self.exit = make(chan error)
+	self.caller = caller
+	// 服务未实现 gina.IStopper 时, 服务管理器停机时调用 Stop
+	gina.AttachStopper(caller, self.Stop)
+	self.exit = make(chan error, 1)
+	self.stopped = make(chan error, 1)
 	self.listenAddr = addr
-	self.timeout = timeout
 	self.Engine = engine
-	ginahelper.ServerAddr = addr
+
+	self.config = LoadServerConfig()
+	self.config.ShutdownTimeout = NormalizeShutdownTimeout(self.name, timeout)
+	for _, opt := range opts {
+		opt(&self.config)
+	}
+}
+
+// Name 服务名称, 为 Init 时传入的 caller 的类型名
+func (self *IHttp) Name() string {
+	return self.name
+}
+
+// AddListener 添加额外的监听地址, 需要在 Start 之前调用, 如同时监听 HTTPS 和 Unix socket
+func (self *IHttp) AddListener(listeners ...Listener) {
+	self.listeners = append(self.listeners, listeners...)
+}
+
+// Listeners 获取所有的监听地址, 启动后包含实际监听的地址
+func (self *IHttp) Listeners() []Listener {
+	listeners := make([]Listener, len(self.listeners))
+	copy(listeners, self.listeners)
+
+	return listeners
+}
+
+// URL 获取第一个 tcp 监听的访问地址, 没有时返回第一个监听的地址
+func (self *IHttp) URL() string {
+	for _, listener := range self.listeners {
+		if listener.network() == NetworkTCP {
+			return listener.URL()
+		}
+	}
+	if len(self.listeners) > 0 {
+		return self.listeners[0].URL()
+	}
+
+	return ""
 }
 
+// OnInit 为每个监听地址创建 http.Server
 func (self *IHttp) OnInit() {
-	self.srv = &http.Server{
-		Addr:    self.listenAddr,
-		Handler: self.Engine,
+	servers := make([]*http.Server, len(self.listeners))
+	for i, listener := range self.listeners {
+		srv := self.config.newServer(listener.Addr, self.Engine)
+		if listener.IsTLS() {
+			srv.TLSConfig = listener.Certs.TLSConfig()
+		} else if listener.H2C {
+			protocols := new(http.Protocols)
+			protocols.SetHTTP1(true)
+			protocols.SetUnencryptedHTTP2(true)
+			srv.Protocols = protocols
+		}
+		servers[i] = srv
 	}
+
+	self.mu.Lock()
+	self.servers = servers
+	self.mu.Unlock()
 }
 
+// OnStop 设置服务启动和停机时的回调函数, 各阶段的执行时机见 Phase
 func (self *IHttp) OnStop(data *CallbackMap) {
-	self.stopCallback = data
+	self.hooks = data
 }
 
+// Start 在 Init 指定的地址上启动 HTTP 服务, 同时启动通过 AddListener 添加的监听
 func (self *IHttp) Start() error {
+	if self.listenAddr != "" {
+		self.listeners = append([]Listener{HTTP(self.listenAddr)}, self.listeners...)
+		ginahelper.ServerAddr, ginahelper.ServerIsTLS = self.listenAddr, false
+	}
+
+	return self.serve()
+}
+
+// StartTLS 在 Init 指定的地址上启动 HTTPS 服务, 同时启动通过 AddListener 添加的监听
+func (self *IHttp) StartTLS(certFile, keyFile string) error {
+	if self.listenAddr != "" {
+		self.listeners = append([]Listener{HTTPS(self.listenAddr, certFile, keyFile)}, self.listeners...)
+		ginahelper.ServerAddr, ginahelper.ServerIsTLS = self.listenAddr, true
+	}
+
+	return self.serve()
+}
+
+// 先同步监听所有地址, 任一地址监听失败时关闭已经监听的地址并返回错误
+func (self *IHttp) serve() error {
+	if len(self.listeners) == 0 {
+		return errors.New("未指定任何监听地址")
+	}
+
+	if err := self.hooks.Run(context.Background(), PhaseBeforeStart); err != nil {
+		return err
+	}
+	if err := self.initCerts(); err != nil {
+		return err
+	}
 	self.OnInit()
-	go func() {
-		if err := self.srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			console.Echo.Errorf("❌  错误: 服务启动异常 %s", err)
-			self.exit <- err
+	lns := make([]net.Listener, 0, len(self.listeners))
+	for i, listener := range self.listeners {
+		ln, err := listener.listen()
+		if err != nil {
+			for _, opened := range lns {
+				_ = opened.Close()
+			}
+			self.closeCerts()
+			return err
 		}
-	}()
+		self.listeners[i].bound = ln.Addr()
+		lns = append(lns, ln)
+	}
 
-	self.tls = false
-	ginahelper.ServerIsTLS = false
-	console.Echo.Infof("✅ 提示: 服务 %s 启动成功，地址为: %s\n", self.name, ginahelper.GetServerAddr())
+	servers := self.getServers()
+	for i, listener := range self.listeners {
+		srv, ln := servers[i], lns[i]
+		go func() {
+			var err error
+			if listener.IsTLS() {
+				err = srv.ServeTLS(ln, "", "")
+			} else {
+				err = srv.Serve(ln)
+			}
+			if err != nil && err != http.ErrServerClosed {
+				console.Echo.Errorf("❌  错误: 服务启动异常 %s", err)
+				select {
+				case self.exit <- err:
+				default:
+				}
+			}
+		}()
+		console.Echo.Infof("✅ 提示: 服务 %s 启动成功，地址为: %s\n", self.name, self.listeners[i])
+	}
+	gina.Started(self.caller)
+
+	instancesMu.Lock()
+	instances = append(instances, self)
+	instancesMu.Unlock()
+	defer func() {
+		instancesMu.Lock()
+		instances = slices.DeleteFunc(instances, func(srv *IHttp) bool {
+			return srv == self
+		})
+		instancesMu.Unlock()
+	}()
 
 	return self.running()
 }
 
-func (self *IHttp) StartTLS(certFile, keyFile string) error {
-	self.OnInit()
-	go func() {
-		if err := self.srv.ListenAndServeTLS(certFile, keyFile); err != nil && err != http.ErrServerClosed {
-			console.Echo.Errorf("❌  错误: 服务启动异常 %s", err)
-			self.exit <- err
+// Stop 优雅停止服务, 同时停止所有监听, 等待处理中的请求完成, 一般在服务的 OnStop 中调用
+// 停止监听前执行 PhaseBeforeShutdown 阶段的回调, 请求全部完成后执行 PhaseAfterDrain 阶段的回调, 回调受 ctx 的截止时间限制
+func (self *IHttp) Stop(ctx context.Context) (err error) {
+	servers := self.getServers()
+	if len(servers) == 0 {
+		return nil
+	}
+
+	self.stopOnce.Do(func() {
+		hookErr := self.hooks.Run(ctx, PhaseBeforeShutdown)
+		shutdownCtx := ctx
+		if self.config.ShutdownTimeout > 0 {
+			var cancel context.CancelFunc
+			shutdownCtx, cancel = context.WithTimeout(ctx, self.config.ShutdownTimeout)
+			defer cancel()
 		}
-	}()
 
-	self.tls = true
-	ginahelper.ServerIsTLS = true
-	console.Echo.Infof("✅ 提示: 服务 %s 启动成功，地址为: %s\n", self.name, ginahelper.GetServerAddr())
+		var wg sync.WaitGroup
+		errs := make([]error, len(servers))
+		for i, srv := range servers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = srv.Shutdown(shutdownCtx)
+			}()
+		}
+		wg.Wait()
+		self.closeCerts()
+		if err = errors.Join(errs...); err != nil {
+			console.Echo.Warnf("⚠️ 警告: 服务停机失败: %s\n", err)
+		}
+		err = errors.Join(err, hookErr, self.hooks.Run(ctx, PhaseAfterDrain))
+		self.stopped <- err
+	})
 
-	return self.running()
+	return
+}
+
+// 阻塞直到服务异常退出或者通过 Stop 停止
+func (self *IHttp) getServers() []*http.Server {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	return self.servers
 }
 
 func (self *IHttp) running() error {
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-
-	for {
-		select {
-		case _, _ = <-self.exit:
-			self.stopCallback.Foreach()
-			return nil
-		case <-quit:
-			self.stopCallback.Foreach()
-			ctx, cancel := context.WithTimeout(context.Background(), time.Second*self.timeout)
-			defer cancel()
-			if err := self.srv.Shutdown(ctx); err != nil {
-				console.Echo.Warnf("⚠️ 警告: 服务停机失败: %s\n", err)
+	select {
+	case err := <-self.exit:
+		return err
+	case err := <-self.stopped:
+		if err != nil {
+			return err
+		}
+
+		console.Echo.Infof("✅ 提示: 服务 %s 已成功关闭\n", self.name)
+		return nil
+	}
+}
 
+// 为 HTTPS 监听加载证书并监听证书文件的变化, 证书更新后无需重启服务
+func (self *IHttp) initCerts() error {
+	for i, listener := range self.listeners {
+		if !listener.IsTLS() {
+			continue
+		}
+		if listener.Certs == nil {
+			certs, err := NewCertProvider(CertPair{CertFile: listener.CertFile, KeyFile: listener.KeyFile})
+			if err != nil {
+				self.closeCerts()
 				return err
 			}
+			self.listeners[i].Certs = certs
+			self.listeners[i].ownsCerts = true
+		}
+		if err := self.listeners[i].Certs.Watch(); err != nil {
+			console.Echo.Warnf("⚠️ 警告: %s, 证书更新后需要重启服务\n", err)
+		}
+	}
+
+	return nil
+}
 
-			console.Echo.Infof("✅ 提示: 服务 %s 已成功关闭\n", self.name)
-			return nil
+// 只关闭 IHttp 自己创建的证书提供者, 通过 HTTPSWithCerts 传入的由调用方关闭
+func (self *IHttp) closeCerts() {
+	for _, listener := range self.listeners {
+		if listener.ownsCerts {
+			_ = listener.Certs.Close()
 		}
 	}
 }
@@ -0,0 +1,26 @@
+package httpmodule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 启动过程中收到停机信号, Stop 与创建 http.Server 同时执行
+func TestStopDuringInit(t *testing.T) {
+	srv := &IHttp{}
+	srv.Init(srv, "127.0.0.1:0", time.Second, gin.New())
+	srv.AddListener(HTTP("127.0.0.1:0"))
+
+	done := make(chan struct{})
+	go func() {
+		srv.OnInit()
+		close(done)
+	}()
+	if err := srv.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+}
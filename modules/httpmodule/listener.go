@@ -0,0 +1,108 @@
+package httpmodule
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/soryetong/greasyx/ginahelper"
+	"github.com/soryetong/greasyx/libs/ginagrace"
+)
+
+// 监听的网络类型
+const (
+	NetworkTCP  = "tcp"
+	NetworkUnix = "unix"
+)
+
+// Listener 服务监听的地址, 同一个 IHttp 可以同时监听多个地址, 共用同一个 gin.Engine
+type Listener struct {
+	Network  string // tcp 或 unix, 为空时为 tcp
+	Addr     string // tcp 为 host:port, unix 为 socket 文件路径
+	CertFile string // 证书和私钥都不为空时使用 HTTPS, 证书文件变化时自动重新加载
+	KeyFile  string
+	Certs    *CertProvider // 自定义的证书提供者, 用于多证书 SNI 或 mTLS, 优先于 CertFile 和 KeyFile, 由调用方负责关闭
+	H2C      bool          // 是否支持明文的 HTTP/2, 只对非 HTTPS 的监听生效
+
+	bound     net.Addr // 启动后实际监听的地址, 如端口为 0 时系统分配的端口
+	ownsCerts bool     // Certs 是否由 IHttp 通过 CertFile 和 KeyFile 创建, 停止服务时只关闭自己创建的
+}
+
+// HTTP 监听 HTTP
+func HTTP(addr string) Listener {
+	return Listener{Network: NetworkTCP, Addr: addr}
+}
+
+// HTTPS 监听 HTTPS, 同时支持 HTTP/1.1 和 HTTP/2
+func HTTPS(addr, certFile, keyFile string) Listener {
+	return Listener{Network: NetworkTCP, Addr: addr, CertFile: certFile, KeyFile: keyFile}
+}
+
+// HTTPSWithCerts 使用自定义的证书提供者监听 HTTPS, 如多个域名的证书或者需要校验客户端证书
+func HTTPSWithCerts(addr string, certs *CertProvider) Listener {
+	return Listener{Network: NetworkTCP, Addr: addr, Certs: certs}
+}
+
+// Unix 监听 Unix domain socket, 如给同一个 Pod 中的 sidecar 使用, 已存在的 socket 文件会被删除
+func Unix(path string) Listener {
+	return Listener{Network: NetworkUnix, Addr: path}
+}
+
+// H2C 监听 HTTP, 同时支持明文的 HTTP/2, 适合内部服务之间的调用
+func H2C(addr string) Listener {
+	return Listener{Network: NetworkTCP, Addr: addr, H2C: true}
+}
+
+// IsTLS 是否使用 HTTPS
+func (self Listener) IsTLS() bool {
+	return self.Certs != nil || (self.CertFile != "" && self.KeyFile != "")
+}
+
+// BoundAddr 实际监听的地址, 未启动时为配置的地址
+func (self Listener) BoundAddr() string {
+	if self.bound != nil {
+		return self.bound.String()
+	}
+
+	return self.Addr
+}
+
+// URL 访问地址, 如 http://192.168.1.2:8080, 未指定 host 时使用本机 IP, unix socket 为 unix:/path/to.sock
+func (self Listener) URL() string {
+	if self.network() == NetworkUnix {
+		return "unix:" + self.Addr
+	}
+
+	scheme := "http"
+	if self.IsTLS() {
+		scheme = "https"
+	}
+
+	addr := self.BoundAddr()
+	host, port, err := net.SplitHostPort(addr)
+	if err == nil && (host == "" || host == "::" || host == "0.0.0.0") {
+		addr = net.JoinHostPort(ginahelper.GetLocalIP(), port)
+	}
+
+	return fmt.Sprintf("%s://%s", scheme, addr)
+}
+
+func (self Listener) String() string {
+	if self.H2C && !self.IsTLS() {
+		return self.URL() + " (h2c)"
+	}
+
+	return self.URL()
+}
+
+func (self Listener) network() string {
+	if self.Network == "" {
+		return NetworkTCP
+	}
+
+	return self.Network
+}
+
+// 重启时优先使用父进程传递的监听
+func (self Listener) listen() (net.Listener, error) {
+	return ginagrace.Listen(self.network(), self.Addr)
+}
@@ -0,0 +1,136 @@
+package httpmodule
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/soryetong/greasyx/console"
+	"github.com/soryetong/greasyx/ginahelper"
+	"github.com/spf13/viper"
+)
+
+// ServerConfig http.Server 的参数, 为 0 时表示不限制, 与 http.Server 一致
+type ServerConfig struct {
+	ReadHeaderTimeout time.Duration // 读取请求头的超时时间, 防止慢速攻击 (slowloris)
+	ReadTimeout       time.Duration // 读取整个请求的超时时间, 包含请求体, 默认不限制
+	WriteTimeout      time.Duration // 写入响应的超时时间, 默认不限制, 设置后 SSE 等长时间的流式响应会被中断
+	IdleTimeout       time.Duration // keep-alive 连接的空闲超时时间
+	MaxHeaderBytes    int           // 请求头的最大字节数
+	ShutdownTimeout   time.Duration // 停机时等待处理中的请求完成的最长时间, 为 0 时只受 App.ShutdownTimeout 的限制
+}
+
+// 默认值, 未配置 App.Server 时使用, 读取请求和写入响应的超时时间与业务相关, 默认不限制
+const (
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultIdleTimeout       = 120 * time.Second
+	DefaultMaxHeaderBytes    = http.DefaultMaxHeaderBytes
+)
+
+// DefaultServerConfig 默认的 http.Server 参数
+func DefaultServerConfig() ServerConfig {
+	return ServerConfig{
+		ReadHeaderTimeout: DefaultReadHeaderTimeout,
+		IdleTimeout:       DefaultIdleTimeout,
+		MaxHeaderBytes:    DefaultMaxHeaderBytes,
+	}
+}
+
+// LoadServerConfig 从 App.Server 中读取 http.Server 的参数, 时间如 "10s", 数字按秒处理, 未配置的使用默认值
+func LoadServerConfig() ServerConfig {
+	config := DefaultServerConfig()
+	duration := func(key string, value *time.Duration) {
+		if !viper.IsSet(key) {
+			return
+		}
+		timeout, err := ginahelper.ToDuration(viper.Get(key))
+		if err != nil {
+			console.Echo.Warnf("⚠️ 警告: %s 配置错误: %s, 使用默认值 %s\n", key, err, *value)
+			return
+		}
+		*value = timeout
+	}
+	duration("App.Server.ReadHeaderTimeout", &config.ReadHeaderTimeout)
+	duration("App.Server.ReadTimeout", &config.ReadTimeout)
+	duration("App.Server.WriteTimeout", &config.WriteTimeout)
+	duration("App.Server.IdleTimeout", &config.IdleTimeout)
+	if viper.IsSet("App.Server.MaxHeaderBytes") {
+		config.MaxHeaderBytes = viper.GetInt("App.Server.MaxHeaderBytes")
+	}
+
+	return config
+}
+
+// NormalizeShutdownTimeout 处理 Init 传入的停机超时时间, 兼容以前直接传入秒数的写法, 如 5
+// 小于 1ms 的值按秒处理并提示改为 5*time.Second 的写法, 以后的版本会移除这个兼容
+func NormalizeShutdownTimeout(name string, timeout time.Duration) time.Duration {
+	if timeout <= 0 || timeout >= time.Millisecond {
+		return timeout
+	}
+	console.Echo.Warnf("⚠️ 警告: %s 的停机超时时间 %d 按秒处理, 该写法已废弃, 请改为 %d*time.Second\n", name, int64(timeout), int64(timeout))
+
+	return timeout * time.Second
+}
+
+// ServerOption 通过代码设置 http.Server 的参数, 优先于 App.Server 的配置
+type ServerOption func(*ServerConfig)
+
+// WithServerConfig 整体替换 http.Server 的参数
+func WithServerConfig(config ServerConfig) ServerOption {
+	return func(c *ServerConfig) {
+		*c = config
+	}
+}
+
+// WithReadHeaderTimeout 读取请求头的超时时间
+func WithReadHeaderTimeout(timeout time.Duration) ServerOption {
+	return func(c *ServerConfig) {
+		c.ReadHeaderTimeout = timeout
+	}
+}
+
+// WithReadTimeout 读取整个请求的超时时间
+func WithReadTimeout(timeout time.Duration) ServerOption {
+	return func(c *ServerConfig) {
+		c.ReadTimeout = timeout
+	}
+}
+
+// WithWriteTimeout 写入响应的超时时间
+func WithWriteTimeout(timeout time.Duration) ServerOption {
+	return func(c *ServerConfig) {
+		c.WriteTimeout = timeout
+	}
+}
+
+// WithIdleTimeout keep-alive 连接的空闲超时时间
+func WithIdleTimeout(timeout time.Duration) ServerOption {
+	return func(c *ServerConfig) {
+		c.IdleTimeout = timeout
+	}
+}
+
+// WithMaxHeaderBytes 请求头的最大字节数
+func WithMaxHeaderBytes(size int) ServerOption {
+	return func(c *ServerConfig) {
+		c.MaxHeaderBytes = size
+	}
+}
+
+// WithShutdownTimeout 停机时等待处理中的请求完成的最长时间
+func WithShutdownTimeout(timeout time.Duration) ServerOption {
+	return func(c *ServerConfig) {
+		c.ShutdownTimeout = timeout
+	}
+}
+
+func (self ServerConfig) newServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: self.ReadHeaderTimeout,
+		ReadTimeout:       self.ReadTimeout,
+		WriteTimeout:      self.WriteTimeout,
+		IdleTimeout:       self.IdleTimeout,
+		MaxHeaderBytes:    self.MaxHeaderBytes,
+	}
+}
@@ -0,0 +1,26 @@
+package httpmodule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizeShutdownTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		want    time.Duration
+	}{
+		{name: "不限制", timeout: 0, want: 0},
+		{name: "以前传入的秒数", timeout: 5, want: 5 * time.Second},
+		{name: "时间", timeout: 5 * time.Second, want: 5 * time.Second},
+		{name: "刚好 1ms", timeout: time.Millisecond, want: time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeShutdownTimeout("test", tt.timeout); got != tt.want {
+				t.Fatalf("停机超时时间为 %s, 应该为 %s", got, tt.want)
+			}
+		})
+	}
+}
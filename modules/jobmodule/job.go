@@ -0,0 +1,289 @@
+package jobmodule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/soryetong/greasyx/console"
+	"github.com/soryetong/greasyx/gina"
+	"github.com/soryetong/greasyx/ginahelper"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	console.AppendModule(&jobModule{}, jobCmd)
+}
+
+var jobCmd = &cobra.Command{
+	Use:   "Jobs",
+	Short: "Init Jobs",
+	Long:  `加载Jobs模块之后，可以通过 jobmodule.Enqueue 投递后台任务，注册了处理函数时会随 Start 一起启动消费者`,
+}
+
+type jobModule struct{}
+
+func (self *jobModule) Name() string {
+	return "Jobs"
+}
+
+// 任务存储在 Redis 中, 需要在 Redis 模块之后加载
+func (self *jobModule) DependsOn() []string {
+	return []string{gina.ModuleName, "Redis"}
+}
+
+// 只投递任务的服务不需要启动消费者
+func (self *jobModule) Init() error {
+	viper.SetDefault("Jobs.Concurrency", 10)
+	viper.SetDefault("Jobs.Queues", []string{DefaultQueue})
+	viper.SetDefault("Jobs.PollInterval", 500)
+	viper.SetDefault("Jobs.Lease", DefaultLease.String())
+
+	if len(Handlers()) > 0 {
+		lease, err := ginahelper.ToDuration(viper.Get("Jobs.Lease"))
+		if err != nil {
+			return fmt.Errorf("Jobs.Lease 配置错误: %w", err)
+		}
+		gina.Register(newWorker(
+			viper.GetStringSlice("Jobs.Queues"),
+			viper.GetInt("Jobs.Concurrency"),
+			time.Duration(viper.GetInt("Jobs.PollInterval"))*time.Millisecond,
+			lease,
+		))
+	}
+	console.Echo.Infof("✅ 提示: Jobs模块加载成功, 你可以使用 `jobmodule.Enqueue` 投递后台任务\n")
+
+	return nil
+}
+
+func (self *jobModule) ConfigSchema() []gina.ConfigField {
+	return []gina.ConfigField{
+		{Key: "Jobs.Concurrency", Type: gina.ConfigTypeInt},
+		{Key: "Jobs.Queues", Type: gina.ConfigTypeArray},
+		{Key: "Jobs.PollInterval", Type: gina.ConfigTypeInt},
+		{Key: "Jobs.Lease", Type: gina.ConfigTypeDuration},
+	}
+}
+
+func (self *jobModule) Close() error {
+	return nil
+}
+
+const (
+	// DefaultQueue 未指定队列时使用的队列
+	DefaultQueue = "default"
+	// DefaultMaxAttempts 默认的最大执行次数, 包含第一次执行
+	DefaultMaxAttempts = 5
+	// DefaultUniqueTTL Unique 未指定有效期时唯一键的有效期, 避免任务丢失后唯一键永远不过期
+	DefaultUniqueTTL = 24 * time.Hour
+	// DefaultLease 取出的任务的租约时长, 消费者每 1/3 租约续约一次, 异常退出后最多经过一个租约任务会被放回队列
+	DefaultLease = time.Minute
+
+	keyPrefix = "gina:jobs:"
+)
+
+// ErrDuplicate 相同唯一键的任务还未执行完成
+var ErrDuplicate = errors.New("相同唯一键的任务已存在")
+
+// Job 存储在 Redis 中的任务
+type Job struct {
+	Id          string          `json:"id"`
+	Type        string          `json:"type"`
+	Queue       string          `json:"queue"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	UniqueKey   string          `json:"unique_key,omitempty"`
+	EnqueuedAt  time.Time       `json:"enqueued_at"`
+	LastError   string          `json:"last_error,omitempty"`
+}
+
+// Option 投递任务时的可选配置
+type Option func(*enqueueOptions)
+
+type enqueueOptions struct {
+	queue       string
+	delay       time.Duration
+	maxAttempts int
+	uniqueKey   string
+	uniqueTTL   time.Duration
+}
+
+// Delay 延迟执行任务
+func Delay(delay time.Duration) Option {
+	return func(o *enqueueOptions) {
+		o.delay = delay
+	}
+}
+
+// Queue 投递到指定的队列, 消费者只处理 Jobs.Queues 中配置的队列
+func Queue(queue string) Option {
+	return func(o *enqueueOptions) {
+		o.queue = queue
+	}
+}
+
+// MaxAttempts 最大执行次数, 超过后进入死信队列
+func MaxAttempts(attempts int) Option {
+	return func(o *enqueueOptions) {
+		o.maxAttempts = attempts
+	}
+}
+
+// Unique 相同 key 的任务在执行完成或进入死信队列之前只能投递一次, ttl 为唯一键的最长有效期, 不大于 0 时使用 DefaultUniqueTTL
+func Unique(key string, ttl time.Duration) Option {
+	return func(o *enqueueOptions) {
+		o.uniqueKey = key
+		o.uniqueTTL = ttl
+		if ttl <= 0 {
+			o.uniqueTTL = DefaultUniqueTTL
+		}
+	}
+}
+
+// Enqueue 投递后台任务, payload 会被序列化为 JSON, 需要使用 gina.Rdb
+func Enqueue(ctx context.Context, typ string, payload any, opts ...Option) (*Job, error) {
+	if gina.Rdb == nil {
+		return nil, fmt.Errorf("投递任务 %s 失败: 请先加载Redis模块", typ)
+	}
+
+	o := &enqueueOptions{queue: DefaultQueue, maxAttempts: DefaultMaxAttempts}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("任务 %s 的参数序列化失败: %w", typ, err)
+	}
+	job := &Job{
+		Id:          ginahelper.GenerateNoWhippletreeUuid(),
+		Type:        typ,
+		Queue:       o.queue,
+		Payload:     data,
+		MaxAttempts: o.maxAttempts,
+		UniqueKey:   o.uniqueKey,
+		EnqueuedAt:  time.Now(),
+	}
+
+	if job.UniqueKey != "" {
+		ok, err := gina.Rdb.SetNX(ctx, uniqueKey(job.UniqueKey), job.Id, o.uniqueTTL).Result()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrDuplicate
+		}
+	}
+
+	if err = schedule(ctx, job, time.Now().Add(o.delay)); err != nil {
+		if job.UniqueKey != "" {
+			gina.Rdb.Del(ctx, uniqueKey(job.UniqueKey))
+		}
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// DeadLetters 获取队列中最近进入死信队列的任务, 最多返回 limit 个
+func DeadLetters(ctx context.Context, queue string, limit int64) ([]*Job, error) {
+	items, err := gina.Rdb.LRange(ctx, deadKey(queue), 0, limit-1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*Job, 0, len(items))
+	for _, item := range items {
+		job := new(Job)
+		if err = json.Unmarshal([]byte(item), job); err != nil {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// HandlerFunc 任务的处理函数, 返回错误时按指数退避重试
+type HandlerFunc func(ctx context.Context, job *Job) error
+
+var (
+	handlerMu sync.RWMutex
+	handlers  = make(map[string]HandlerFunc)
+)
+
+// Handle 注册任务的处理函数, 同一类型的任务只能注册一次
+func Handle(typ string, fn HandlerFunc) {
+	handlerMu.Lock()
+	defer handlerMu.Unlock()
+	if _, exists := handlers[typ]; exists {
+		console.Echo.Fatalf("❌ 错误: 任务 `%s` 的处理函数重复注册\n", typ)
+	}
+	handlers[typ] = fn
+}
+
+// HandleFunc 注册任务的处理函数, 任务参数按 T 解析
+func HandleFunc[T any](typ string, fn func(ctx context.Context, payload T) error) {
+	Handle(typ, func(ctx context.Context, job *Job) error {
+		var payload T
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return fmt.Errorf("任务参数解析失败: %w", err)
+		}
+		return fn(ctx, payload)
+	})
+}
+
+// Handlers 获取已注册处理函数的任务类型
+func Handlers() []string {
+	handlerMu.RLock()
+	defer handlerMu.RUnlock()
+
+	types := make([]string, 0, len(handlers))
+	for typ := range handlers {
+		types = append(types, typ)
+	}
+
+	return types
+}
+
+func getHandler(typ string) HandlerFunc {
+	handlerMu.RLock()
+	defer handlerMu.RUnlock()
+
+	return handlers[typ]
+}
+
+func schedule(ctx context.Context, job *Job, at time.Time) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	return gina.Rdb.ZAdd(ctx, scheduledKey(job.Queue), &redis.Z{
+		Score:  float64(at.UnixMilli()),
+		Member: data,
+	}).Err()
+}
+
+// 同一个队列的键使用相同的 hash tag, Redis 集群中脚本操作的多个键需要在同一个 slot
+func scheduledKey(queue string) string {
+	return keyPrefix + "{" + queue + "}:scheduled"
+}
+
+func processingKey(queue string) string {
+	return keyPrefix + "{" + queue + "}:processing"
+}
+
+func deadKey(queue string) string {
+	return keyPrefix + "{" + queue + "}:dead"
+}
+
+func uniqueKey(key string) string {
+	return keyPrefix + "unique:" + key
+}
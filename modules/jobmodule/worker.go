@@ -0,0 +1,417 @@
+package jobmodule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/soryetong/greasyx/console"
+	"github.com/soryetong/greasyx/gina"
+	"go.uber.org/zap"
+)
+
+const (
+	// 重试间隔的基数和上限, 第 n 次重试的间隔为 base * 2^(n-1), 另外加上最多 20% 的随机抖动
+	retryBase     = time.Second
+	retryMaxDelay = time.Hour
+	// 死信队列最多保留的任务数
+	deadLetterSize = 1000
+	// 停机超时取消执行中的任务后, 等待它们放回队列的最长时间, 不响应取消的任务在租约过期后由其他消费者回收
+	cancelGrace = time.Second
+)
+
+// 确认任务的方式
+const (
+	ackDone  = "done"  // 执行成功, 只从处理中的集合删除
+	ackRetry = "retry" // 放回队列
+	ackDead  = "dead"  // 写入死信队列
+)
+
+// 取出到期的任务并移到处理中的集合, 分数为租约的到期时间, 消费者退出后任务不会丢失
+// 取出和移动在同一个脚本中执行, 保证多个消费者不会取到同一个任务
+var popScript = redis.NewScript(`
+local items = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, ARGV[2])
+for _, item in ipairs(items) do
+	redis.call("ZREM", KEYS[1], item)
+	redis.call("ZADD", KEYS[2], ARGV[3], item)
+end
+return items
+`)
+
+// 从处理中的集合删除任务, 再按 ARGV[3] 放回队列或写入死信队列
+// 任务已经不在处理中的集合时返回 0, 说明租约已过期并被回收, 此时不做任何操作
+var ackScript = redis.NewScript(`
+if redis.call("ZREM", KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+if ARGV[3] == "retry" then
+	redis.call("ZADD", KEYS[2], ARGV[4], ARGV[2])
+elseif ARGV[3] == "dead" then
+	redis.call("LPUSH", KEYS[3], ARGV[2])
+	redis.call("LTRIM", KEYS[3], 0, tonumber(ARGV[5]) - 1)
+end
+return 1
+`)
+
+// Worker 任务消费者, 实现了 gina.IService 和 gina.IStopper
+// 取出的任务在执行期间保存在处理中的集合, 并定期续约, 消费者异常退出后由其他消费者在租约过期时放回队列
+type Worker struct {
+	queues       []string
+	concurrency  int
+	pollInterval time.Duration
+	lease        time.Duration
+
+	mu       sync.Mutex
+	inflight map[*delivery]struct{}
+
+	slots   chan struct{}
+	wg      sync.WaitGroup
+	ctx     context.Context // 执行中任务的 ctx, 停机超时后取消
+	cancel  context.CancelFunc
+	quit    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// 取出的任务, raw 为处理中的集合中的成员, 确认时按它删除
+type delivery struct {
+	job *Job
+	raw string
+}
+
+func newWorker(queues []string, concurrency int, pollInterval, lease time.Duration) *Worker {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if pollInterval <= 0 {
+		pollInterval = 500 * time.Millisecond
+	}
+	// 租约太短时续约来不及执行, 任务会被重复执行
+	if lease < time.Second {
+		lease = DefaultLease
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Worker{
+		queues:       queues,
+		concurrency:  concurrency,
+		pollInterval: pollInterval,
+		lease:        lease,
+		inflight:     make(map[*delivery]struct{}),
+		slots:        make(chan struct{}, concurrency),
+		ctx:          ctx,
+		cancel:       cancel,
+		quit:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+	}
+}
+
+// OnStart 按配置的队列顺序轮询到期的任务, 并回收租约过期的任务, 阻塞直到 OnStop 被调用
+func (self *Worker) OnStart() error {
+	defer close(self.stopped)
+	console.Echo.Infof("✅ 提示: 任务消费者启动成功, 队列: %v, 并发数: %d\n", self.queues, self.concurrency)
+
+	// 续约在停机等待执行中的任务时也要继续, 直到 OnStop 结束
+	go self.keepalive()
+	self.reap()
+	gina.Started(self)
+
+	ticker := time.NewTicker(self.pollInterval)
+	defer ticker.Stop()
+	reapTicker := time.NewTicker(self.lease / 2)
+	defer reapTicker.Stop()
+	for {
+		// 取到任务时立即继续取, 队列为空时等待下一次轮询
+		if self.fetch() > 0 {
+			select {
+			case <-self.quit:
+				return nil
+			default:
+				continue
+			}
+		}
+
+		select {
+		case <-self.quit:
+			return nil
+		case <-reapTicker.C:
+			self.reap()
+		case <-ticker.C:
+		}
+	}
+}
+
+// OnStop 停止获取新的任务, 并等待执行中的任务完成, ctx 到期后取消执行中的任务, 这些任务会重新放回队列
+// 取消后最多再等待 cancelGrace, 不响应取消的任务不会阻塞停机
+func (self *Worker) OnStop(ctx context.Context) error {
+	var err error
+	self.once.Do(func() {
+		close(self.quit)
+		<-self.stopped
+
+		done := make(chan struct{})
+		go func() {
+			self.wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			self.cancel()
+			grace := time.NewTimer(cancelGrace)
+			select {
+			case <-done:
+			case <-grace.C:
+			}
+			grace.Stop()
+			err = fmt.Errorf("等待执行中的任务超时: %w", ctx.Err())
+		}
+		self.cancel()
+	})
+
+	return err
+}
+
+// 按空闲的并发数取出任务并执行, 返回取到的任务数
+func (self *Worker) fetch() int {
+	fetched := 0
+	for _, queue := range self.queues {
+		free := self.concurrency - len(self.slots)
+		if free <= 0 {
+			break
+		}
+
+		now := time.Now()
+		items, err := popScript.Run(context.Background(), gina.Rdb, []string{scheduledKey(queue), processingKey(queue)},
+			now.UnixMilli(), free, now.Add(self.lease).UnixMilli()).StringSlice()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			gina.Log.Error("[Jobs.Fetch] 获取任务失败", zap.String("queue", queue), zap.Error(err))
+			continue
+		}
+
+		for _, item := range items {
+			job := new(Job)
+			if err = json.Unmarshal([]byte(item), job); err != nil {
+				gina.Log.Error("[Jobs.Fetch] 任务格式错误, 已丢弃", zap.String("queue", queue), zap.String("job", item))
+				gina.Rdb.ZRem(context.Background(), processingKey(queue), item)
+				continue
+			}
+
+			fetched++
+			d := &delivery{job: job, raw: item}
+			self.track(d, true)
+			self.slots <- struct{}{}
+			self.wg.Add(1)
+			go func() {
+				defer func() {
+					self.track(d, false)
+					<-self.slots
+					self.wg.Done()
+				}()
+				self.process(d)
+			}()
+		}
+	}
+
+	return fetched
+}
+
+func (self *Worker) process(d *delivery) {
+	job := d.job
+	handler := getHandler(job.Type)
+	if handler == nil {
+		self.fail(d, fmt.Errorf("任务 %s 未注册处理函数", job.Type))
+		return
+	}
+
+	job.Attempts++
+	err := call(self.ctx, handler, job)
+	if err == nil {
+		self.ack(d, ackDone, time.Time{})
+		return
+	}
+
+	// 停机时被取消的任务不计入执行次数, 重新放回队列
+	if self.ctx.Err() != nil {
+		job.Attempts--
+		self.ack(d, ackRetry, time.Now())
+		return
+	}
+
+	self.fail(d, err)
+}
+
+// 执行任务并捕获 panic, panic 和返回错误一样会重试
+func call(ctx context.Context, handler HandlerFunc, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			gina.Log.Error("[Jobs.Run] 任务发生panic", zap.String("id", job.Id), zap.String("type", job.Type),
+				zap.Any("panic", r), zap.String("stack", string(debug.Stack())))
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return handler(ctx, job)
+}
+
+// 未超过最大执行次数时按指数退避重试, 否则进入死信队列
+func (self *Worker) fail(d *delivery, err error) {
+	job := d.job
+	job.LastError = err.Error()
+	if job.Attempts < job.MaxAttempts {
+		delay := backoff(job.Attempts)
+		gina.Log.Warn("[Jobs.Run] 任务执行失败, 稍后重试", zap.String("id", job.Id), zap.String("type", job.Type),
+			zap.Int("attempts", job.Attempts), zap.Duration("delay", delay), zap.Error(err))
+		self.ack(d, ackRetry, time.Now().Add(delay))
+		return
+	}
+
+	gina.Log.Error("[Jobs.Run] 任务超过最大执行次数, 进入死信队列", zap.String("id", job.Id), zap.String("type", job.Type),
+		zap.Int("attempts", job.Attempts), zap.Error(err))
+	self.ack(d, ackDead, time.Time{})
+}
+
+// 确认任务, 租约已被回收时任务已经放回队列, 不再处理, 任务会再执行一次
+func (self *Worker) ack(d *delivery, mode string, at time.Time) {
+	job := d.job
+	ok, err := settle(context.Background(), job, d.raw, mode, at)
+	if err != nil {
+		gina.Log.Error("[Jobs.Ack] 确认任务失败, 租约过期后会重新执行", zap.String("id", job.Id), zap.String("type", job.Type),
+			zap.String("mode", mode), zap.Error(err))
+		return
+	}
+	if !ok {
+		gina.Log.Warn("[Jobs.Ack] 任务的租约已过期并被回收, 任务会重新执行", zap.String("id", job.Id), zap.String("type", job.Type))
+		return
+	}
+	if mode != ackRetry {
+		release(job)
+	}
+}
+
+// 从处理中的集合删除 raw, 并按 mode 放回队列或写入死信队列, raw 已不在处理中的集合时返回 false
+func settle(ctx context.Context, job *Job, raw, mode string, at time.Time) (bool, error) {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return false, err
+	}
+
+	ret, err := ackScript.Run(ctx, gina.Rdb, []string{processingKey(job.Queue), scheduledKey(job.Queue), deadKey(job.Queue)},
+		raw, data, mode, at.UnixMilli(), deadLetterSize).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return ret == 1, nil
+}
+
+// 任务结束后释放唯一键, 只释放自己持有的唯一键
+func release(job *Job) {
+	if job.UniqueKey == "" {
+		return
+	}
+
+	ctx := context.Background()
+	key := uniqueKey(job.UniqueKey)
+	if id, _ := gina.Rdb.Get(ctx, key).Result(); id == job.Id {
+		gina.Rdb.Del(ctx, key)
+	}
+}
+
+func (self *Worker) track(d *delivery, add bool) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if add {
+		self.inflight[d] = struct{}{}
+	} else {
+		delete(self.inflight, d)
+	}
+}
+
+// 定期延长执行中任务的租约, 直到 OnStop 结束, 只更新仍在处理中的集合中的任务
+func (self *Worker) keepalive() {
+	ticker := time.NewTicker(self.lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-self.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		self.mu.Lock()
+		deliveries := make([]*delivery, 0, len(self.inflight))
+		for d := range self.inflight {
+			deliveries = append(deliveries, d)
+		}
+		self.mu.Unlock()
+
+		expireAt := float64(time.Now().Add(self.lease).UnixMilli())
+		for _, d := range deliveries {
+			err := gina.Rdb.ZAddXX(context.Background(), processingKey(d.job.Queue), &redis.Z{Score: expireAt, Member: d.raw}).Err()
+			if err != nil {
+				gina.Log.Error("[Jobs.Lease] 任务续约失败", zap.String("id", d.job.Id), zap.String("type", d.job.Type), zap.Error(err))
+			}
+		}
+	}
+}
+
+// 把租约过期的任务放回队列, 这些任务的消费者已经异常退出, 计入一次执行次数, 超过最大执行次数时进入死信队列
+func (self *Worker) reap() {
+	ctx := context.Background()
+	now := time.Now()
+	for _, queue := range self.queues {
+		items, err := gina.Rdb.ZRangeByScore(ctx, processingKey(queue), &redis.ZRangeBy{
+			Min: "-inf", Max: strconv.FormatInt(now.UnixMilli(), 10),
+		}).Result()
+		if err != nil {
+			gina.Log.Error("[Jobs.Reap] 获取租约过期的任务失败", zap.String("queue", queue), zap.Error(err))
+			continue
+		}
+
+		for _, item := range items {
+			job := new(Job)
+			if err = json.Unmarshal([]byte(item), job); err != nil {
+				gina.Rdb.ZRem(ctx, processingKey(queue), item)
+				continue
+			}
+
+			job.Attempts++
+			job.LastError = "任务的租约已过期, 消费者可能已异常退出"
+			mode := ackRetry
+			if job.Attempts >= job.MaxAttempts {
+				mode = ackDead
+			}
+			ok, err := settle(ctx, job, item, mode, now)
+			if err != nil {
+				gina.Log.Error("[Jobs.Reap] 回收任务失败", zap.String("id", job.Id), zap.String("type", job.Type), zap.Error(err))
+				continue
+			}
+			if !ok {
+				continue
+			}
+			gina.Log.Warn("[Jobs.Reap] 任务的租约已过期, 已回收", zap.String("id", job.Id), zap.String("type", job.Type),
+				zap.String("mode", mode), zap.Int("attempts", job.Attempts))
+			if mode == ackDead {
+				release(job)
+			}
+		}
+	}
+}
+
+func backoff(attempts int) time.Duration {
+	delay := retryBase << (attempts - 1)
+	if delay <= 0 || delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+
+	return delay + time.Duration(rand.Int64N(int64(delay)/5+1))
+}
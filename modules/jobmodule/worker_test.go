@@ -0,0 +1,254 @@
+package jobmodule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/soryetong/greasyx/gina"
+	"github.com/soryetong/greasyx/ginatest"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		min, max time.Duration
+	}{
+		{1, time.Second, 1200 * time.Millisecond},
+		{2, 2 * time.Second, 2400 * time.Millisecond},
+		{5, 16 * time.Second, 19200 * time.Millisecond},
+		{13, retryMaxDelay, retryMaxDelay * 6 / 5},
+		{100, retryMaxDelay, retryMaxDelay * 6 / 5},
+	}
+	for _, tt := range tests {
+		for range 20 {
+			if got := backoff(tt.attempts); got < tt.min || got > tt.max {
+				t.Fatalf("backoff(%d) = %s, 应该在 [%s, %s] 之间", tt.attempts, got, tt.min, tt.max)
+			}
+		}
+	}
+}
+
+func setup(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	env := ginatest.New(t, nil)
+	return env.Redis()
+}
+
+// 投递一个立即执行的任务并返回
+func enqueue(t *testing.T, typ string, opts ...Option) *Job {
+	t.Helper()
+	job, err := Enqueue(context.Background(), typ, map[string]string{"k": "v"}, opts...)
+	if err != nil {
+		t.Fatalf("投递任务失败: %s", err)
+	}
+
+	return job
+}
+
+func members(t *testing.T, mr *miniredis.Miniredis, key string) []*Job {
+	t.Helper()
+	if !mr.Exists(key) {
+		return nil
+	}
+	items, err := mr.ZMembers(key)
+	if err != nil {
+		t.Fatalf("读取 %s 失败: %s", key, err)
+	}
+
+	jobs := make([]*Job, 0, len(items))
+	for _, item := range items {
+		job := new(Job)
+		if err = json.Unmarshal([]byte(item), job); err != nil {
+			t.Fatalf("任务格式错误: %s", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs
+}
+
+// 执行一轮并等待任务结束
+func runOnce(w *Worker) int {
+	n := w.fetch()
+	w.wg.Wait()
+	return n
+}
+
+func TestWorkerAck(t *testing.T) {
+	mr := setup(t)
+	Handle("test_ack", func(ctx context.Context, job *Job) error { return nil })
+
+	job := enqueue(t, "test_ack", Unique("ack", time.Minute))
+	w := newWorker([]string{DefaultQueue}, 2, 0, time.Minute)
+	if n := runOnce(w); n != 1 {
+		t.Fatalf("取到 %d 个任务, 应该为 1 个", n)
+	}
+
+	if jobs := members(t, mr, processingKey(DefaultQueue)); len(jobs) != 0 {
+		t.Fatalf("执行成功的任务仍在处理中的集合: %v", jobs)
+	}
+	if jobs := members(t, mr, scheduledKey(DefaultQueue)); len(jobs) != 0 {
+		t.Fatalf("执行成功的任务仍在队列中: %v", jobs)
+	}
+	if mr.Exists(uniqueKey("ack")) {
+		t.Fatalf("任务 %s 执行成功后唯一键没有释放", job.Id)
+	}
+}
+
+func TestWorkerRetryAndDeadLetter(t *testing.T) {
+	mr := setup(t)
+	Handle("test_retry", func(ctx context.Context, job *Job) error { return errors.New("boom") })
+
+	job := enqueue(t, "test_retry", MaxAttempts(2), Unique("retry", time.Minute))
+	w := newWorker([]string{DefaultQueue}, 1, 0, time.Minute)
+	runOnce(w)
+
+	scheduled := members(t, mr, scheduledKey(DefaultQueue))
+	if len(scheduled) != 1 || scheduled[0].Attempts != 1 || scheduled[0].LastError != "boom" {
+		t.Fatalf("第一次失败后应该放回队列, 实际为: %+v", scheduled)
+	}
+	if jobs := members(t, mr, processingKey(DefaultQueue)); len(jobs) != 0 {
+		t.Fatalf("重试的任务仍在处理中的集合: %v", jobs)
+	}
+	if !mr.Exists(uniqueKey("retry")) {
+		t.Fatal("重试中的任务不应该释放唯一键")
+	}
+	// 重试的时间在 1s 之后, 这次取不到
+	if n := runOnce(w); n != 0 {
+		t.Fatalf("未到重试时间的任务被取出了 %d 个", n)
+	}
+
+	// 提前到期后再执行一次, 超过最大执行次数进入死信队列
+	raw, _ := mr.ZMembers(scheduledKey(DefaultQueue))
+	_, _ = mr.ZAdd(scheduledKey(DefaultQueue), 0, raw[0])
+	runOnce(w)
+
+	dead, err := DeadLetters(context.Background(), DefaultQueue, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0].Id != job.Id || dead[0].Attempts != 2 {
+		t.Fatalf("超过最大执行次数的任务应该进入死信队列, 实际为: %+v", dead)
+	}
+	if mr.Exists(uniqueKey("retry")) {
+		t.Fatal("进入死信队列的任务应该释放唯一键")
+	}
+}
+
+// 模拟消费者取出任务后异常退出, 任务在租约过期后被放回队列
+func TestWorkerReapExpiredLease(t *testing.T) {
+	mr := setup(t)
+	job := enqueue(t, "test_crash", MaxAttempts(2))
+
+	crashed := func() {
+		now := time.Now()
+		items, err := popScript.Run(context.Background(), gina.Rdb,
+			[]string{scheduledKey(DefaultQueue), processingKey(DefaultQueue)},
+			now.UnixMilli(), 10, now.Add(-time.Second).UnixMilli()).StringSlice()
+		if err != nil || len(items) != 1 {
+			t.Fatalf("取出任务失败: %v, %v", items, err)
+		}
+	}
+	crashed()
+	if jobs := members(t, mr, scheduledKey(DefaultQueue)); len(jobs) != 0 {
+		t.Fatalf("取出的任务仍在队列中: %v", jobs)
+	}
+	if jobs := members(t, mr, processingKey(DefaultQueue)); len(jobs) != 1 {
+		t.Fatalf("取出的任务应该在处理中的集合, 实际为: %v", jobs)
+	}
+
+	w := newWorker([]string{DefaultQueue}, 1, 0, time.Minute)
+	w.reap()
+	scheduled := members(t, mr, scheduledKey(DefaultQueue))
+	if len(scheduled) != 1 || scheduled[0].Id != job.Id || scheduled[0].Attempts != 1 {
+		t.Fatalf("租约过期的任务应该放回队列并计入执行次数, 实际为: %+v", scheduled)
+	}
+	if jobs := members(t, mr, processingKey(DefaultQueue)); len(jobs) != 0 {
+		t.Fatalf("回收后处理中的集合应该为空, 实际为: %v", jobs)
+	}
+
+	// 再次异常退出, 超过最大执行次数进入死信队列
+	crashed()
+	w.reap()
+	dead, _ := DeadLetters(context.Background(), DefaultQueue, 10)
+	if len(dead) != 1 || dead[0].Attempts != 2 {
+		t.Fatalf("多次异常退出的任务应该进入死信队列, 实际为: %+v", dead)
+	}
+}
+
+// 租约未过期的任务不会被回收, 被回收的任务确认时不会再放回队列
+func TestWorkerAckAfterReap(t *testing.T) {
+	mr := setup(t)
+	enqueue(t, "test_late")
+
+	now := time.Now()
+	items, _ := popScript.Run(context.Background(), gina.Rdb,
+		[]string{scheduledKey(DefaultQueue), processingKey(DefaultQueue)},
+		now.UnixMilli(), 10, now.Add(time.Minute).UnixMilli()).StringSlice()
+	w := newWorker([]string{DefaultQueue}, 1, 0, time.Minute)
+	w.reap()
+	if jobs := members(t, mr, processingKey(DefaultQueue)); len(jobs) != 1 {
+		t.Fatalf("租约未过期的任务不应该被回收, 实际为: %v", jobs)
+	}
+
+	job := new(Job)
+	_ = json.Unmarshal([]byte(items[0]), job)
+	_, _ = mr.ZRem(processingKey(DefaultQueue), items[0])
+	ok, err := settle(context.Background(), job, items[0], ackRetry, now)
+	if err != nil || ok {
+		t.Fatalf("已被回收的任务确认时应该返回 false, 实际为: %v, %v", ok, err)
+	}
+	if jobs := members(t, mr, scheduledKey(DefaultQueue)); len(jobs) != 0 {
+		t.Fatalf("已被回收的任务不应该再次放回队列, 实际为: %v", jobs)
+	}
+}
+
+// 任务不响应取消时, 停机超时后不会一直等待
+func TestWorkerStopIgnoresStuckJob(t *testing.T) {
+	setup(t)
+	release := make(chan struct{})
+	Handle("test_stuck", func(ctx context.Context, job *Job) error {
+		<-release
+		return nil
+	})
+
+	enqueue(t, "test_stuck")
+	w := newWorker([]string{DefaultQueue}, 1, 0, time.Minute)
+	if n := w.fetch(); n != 1 {
+		t.Fatalf("取到 %d 个任务, 应该为 1 个", n)
+	}
+	// 测试结束前让任务完成, 避免在 Redis 关闭后确认
+	defer func() {
+		close(release)
+		w.wg.Wait()
+	}()
+	// 没有调用 OnStart, 直接标记轮询已经结束
+	close(w.stopped)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := w.OnStop(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("错误为 %v, 应该为等待超时", err)
+	}
+	if elapsed := time.Since(start); elapsed > cancelGrace+time.Second {
+		t.Fatalf("停机等待了 %s, 不应该一直等待不响应取消的任务", elapsed)
+	}
+}
+
+func TestUniqueDefaultTTL(t *testing.T) {
+	mr := setup(t)
+	enqueue(t, "test_unique", Unique("forever", 0))
+
+	if ttl := mr.TTL(uniqueKey("forever")); ttl != DefaultUniqueTTL {
+		t.Fatalf("ttl 为 0 时唯一键的有效期应该为 %s, 实际为 %s", DefaultUniqueTTL, ttl)
+	}
+	if _, err := Enqueue(context.Background(), "test_unique", nil, Unique("forever", 0)); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("相同唯一键的任务应该返回 ErrDuplicate, 实际为 %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package metricsmodule
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/soryetong/greasyx/console"
+	"github.com/soryetong/greasyx/ginahelper"
+)
+
+// 单独的端口上配置了 Metrics.Token 时校验令牌
+func auth(token string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if authorized(ctx, token, false) {
+			ctx.Next()
+		}
+	}
+}
+
+// 配置了令牌时校验 Authorization: Bearer <token> 或 X-Metrics-Token 请求头, 与 Prometheus 的 authorization 配置一致
+// 没有配置令牌时, localOnly 为 true 则只允许本机的请求, 根据连接的地址判断, 不信任 X-Forwarded-For 等请求头
+// 未通过时返回 false 并中止请求
+func authorized(ctx *gin.Context, token string, localOnly bool) bool {
+	if token == "" {
+		if !localOnly || ginahelper.IsLoopback(ctx.Request.RemoteAddr) {
+			return true
+		}
+		console.Echo.Warnf("⚠️ 警告: 指标只允许本机访问, 拒绝来自 %s 的请求, 需要对外暴露时请配置 Metrics.Token 或 Metrics.Addr\n", ctx.Request.RemoteAddr)
+		ctx.AbortWithStatus(http.StatusForbidden)
+		return false
+	}
+
+	given := strings.TrimPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+	if given == "" {
+		given = ctx.GetHeader("X-Metrics-Token")
+	}
+	if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		console.Echo.Warnf("⚠️ 警告: 指标收到未授权的请求, 来源: %s\n", ctx.Request.RemoteAddr)
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return false
+	}
+
+	return true
+}
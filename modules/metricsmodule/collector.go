@@ -0,0 +1,123 @@
+package metricsmodule
+
+import (
+	"database/sql"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/soryetong/greasyx/gina"
+	"github.com/soryetong/greasyx/libs/ginasrv"
+	"github.com/soryetong/greasyx/modules/cachemodule"
+	"gorm.io/gorm"
+)
+
+// 额外需要统计的缓存, gina.Cache 总会统计
+var caches sync.Map // name(string) => *cachemodule.Cache
+
+// RegisterCache 统计自定义缓存的命中、未命中和淘汰次数, 同名会被覆盖
+func RegisterCache(name string, cache *cachemodule.Cache) {
+	caches.Store(name, cache)
+}
+
+func newDesc(subsystem, name, help string, labels ...string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, name), help, labels, nil)
+}
+
+var (
+	dbOpen              = newDesc("db", "open_connections", "数据库已建立的连接数", "driver", "client")
+	dbInUse             = newDesc("db", "in_use_connections", "数据库正在使用的连接数", "driver", "client")
+	dbIdle              = newDesc("db", "idle_connections", "数据库空闲的连接数", "driver", "client")
+	dbMaxOpen           = newDesc("db", "max_open_connections", "数据库的最大连接数", "driver", "client")
+	dbWaitCount         = newDesc("db", "wait_count_total", "等待空闲连接的次数", "driver", "client")
+	dbWaitDuration      = newDesc("db", "wait_duration_seconds_total", "等待空闲连接的总时间", "driver", "client")
+	dbMaxIdleClosed     = newDesc("db", "max_idle_closed_total", "因超过最大空闲连接数被关闭的连接数", "driver", "client")
+	dbMaxIdleTimeClosed = newDesc("db", "max_idle_time_closed_total", "因超过最大空闲时间被关闭的连接数", "driver", "client")
+	dbMaxLifetimeClosed = newDesc("db", "max_lifetime_closed_total", "因超过最大存活时间被关闭的连接数", "driver", "client")
+
+	redisHits       = newDesc("redis", "pool_hits_total", "从连接池中获取到空闲连接的次数")
+	redisMisses     = newDesc("redis", "pool_misses_total", "连接池中没有空闲连接的次数")
+	redisTimeouts   = newDesc("redis", "pool_timeouts_total", "从连接池中获取连接超时的次数")
+	redisTotalConns = newDesc("redis", "pool_total_connections", "连接池中的连接数")
+	redisIdleConns  = newDesc("redis", "pool_idle_connections", "连接池中空闲的连接数")
+	redisStaleConns = newDesc("redis", "pool_stale_connections_total", "连接池中被移除的过期连接数")
+
+	cacheHits        = newDesc("cache", "hits_total", "缓存命中次数", "cache")
+	cacheMisses      = newDesc("cache", "misses_total", "缓存未命中次数", "cache")
+	cacheEvictions   = newDesc("cache", "evictions_total", "缓存超出容量被淘汰的项数", "cache")
+	cacheExpirations = newDesc("cache", "expirations_total", "缓存过期被清理的项数", "cache")
+	cacheItems       = newDesc("cache", "items", "缓存中的项数", "cache")
+
+	limiterRejected = newDesc("limiter", "rejected_total", "被限流的请求数, 通用限流的 route 为 comm", "route")
+)
+
+// 在采集时读取各组件的统计, 采集前注册的数据库、Redis 和缓存都会统计
+type statsCollector struct{}
+
+func (self *statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		dbOpen, dbInUse, dbIdle, dbMaxOpen, dbWaitCount, dbWaitDuration, dbMaxIdleClosed, dbMaxIdleTimeClosed, dbMaxLifetimeClosed,
+		redisHits, redisMisses, redisTimeouts, redisTotalConns, redisIdleConns, redisStaleConns,
+		cacheHits, cacheMisses, cacheEvictions, cacheExpirations, cacheItems,
+		limiterRejected,
+	} {
+		ch <- desc
+	}
+}
+
+func (self *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	gina.RangeGorm(func(driver string, db *gorm.DB) bool {
+		if sqlDB, err := db.DB(); err == nil {
+			collectDB(ch, sqlDB.Stats(), driver, "gorm")
+		}
+		return true
+	})
+	gina.RangeSqlx(func(driver string, db *sqlx.DB) bool {
+		collectDB(ch, db.Stats(), driver, "sqlx")
+		return true
+	})
+
+	if pooler, ok := gina.Rdb.(interface{ PoolStats() *redis.PoolStats }); ok {
+		stats := pooler.PoolStats()
+		ch <- prometheus.MustNewConstMetric(redisHits, prometheus.CounterValue, float64(stats.Hits))
+		ch <- prometheus.MustNewConstMetric(redisMisses, prometheus.CounterValue, float64(stats.Misses))
+		ch <- prometheus.MustNewConstMetric(redisTimeouts, prometheus.CounterValue, float64(stats.Timeouts))
+		ch <- prometheus.MustNewConstMetric(redisTotalConns, prometheus.GaugeValue, float64(stats.TotalConns))
+		ch <- prometheus.MustNewConstMetric(redisIdleConns, prometheus.GaugeValue, float64(stats.IdleConns))
+		ch <- prometheus.MustNewConstMetric(redisStaleConns, prometheus.CounterValue, float64(stats.StaleConns))
+	}
+
+	if gina.Cache != nil {
+		collectCache(ch, gina.Cache, "gina")
+	}
+	caches.Range(func(key, value any) bool {
+		collectCache(ch, value.(*cachemodule.Cache), key.(string))
+		return true
+	})
+
+	for route, count := range ginasrv.LimiterRejected() {
+		ch <- prometheus.MustNewConstMetric(limiterRejected, prometheus.CounterValue, float64(count), route)
+	}
+}
+
+func collectDB(ch chan<- prometheus.Metric, stats sql.DBStats, labels ...string) {
+	ch <- prometheus.MustNewConstMetric(dbOpen, prometheus.GaugeValue, float64(stats.OpenConnections), labels...)
+	ch <- prometheus.MustNewConstMetric(dbInUse, prometheus.GaugeValue, float64(stats.InUse), labels...)
+	ch <- prometheus.MustNewConstMetric(dbIdle, prometheus.GaugeValue, float64(stats.Idle), labels...)
+	ch <- prometheus.MustNewConstMetric(dbMaxOpen, prometheus.GaugeValue, float64(stats.MaxOpenConnections), labels...)
+	ch <- prometheus.MustNewConstMetric(dbWaitCount, prometheus.CounterValue, float64(stats.WaitCount), labels...)
+	ch <- prometheus.MustNewConstMetric(dbWaitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds(), labels...)
+	ch <- prometheus.MustNewConstMetric(dbMaxIdleClosed, prometheus.CounterValue, float64(stats.MaxIdleClosed), labels...)
+	ch <- prometheus.MustNewConstMetric(dbMaxIdleTimeClosed, prometheus.CounterValue, float64(stats.MaxIdleTimeClosed), labels...)
+	ch <- prometheus.MustNewConstMetric(dbMaxLifetimeClosed, prometheus.CounterValue, float64(stats.MaxLifetimeClosed), labels...)
+}
+
+func collectCache(ch chan<- prometheus.Metric, cache *cachemodule.Cache, name string) {
+	stats := cache.Stats()
+	ch <- prometheus.MustNewConstMetric(cacheHits, prometheus.CounterValue, float64(stats.Hits), name)
+	ch <- prometheus.MustNewConstMetric(cacheMisses, prometheus.CounterValue, float64(stats.Misses), name)
+	ch <- prometheus.MustNewConstMetric(cacheEvictions, prometheus.CounterValue, float64(stats.Evictions), name)
+	ch <- prometheus.MustNewConstMetric(cacheExpirations, prometheus.CounterValue, float64(stats.Expirations), name)
+	ch <- prometheus.MustNewConstMetric(cacheItems, prometheus.GaugeValue, float64(cache.Len()), name)
+}
@@ -0,0 +1,126 @@
+package metricsmodule
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/soryetong/greasyx/console"
+	"github.com/soryetong/greasyx/gina"
+	"github.com/soryetong/greasyx/ginahelper"
+	"github.com/soryetong/greasyx/modules/httpmodule"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	registry.MustRegister(httpRequests, httpDuration, httpInFlight, &statsCollector{})
+	console.AppendModule(&metricsModule{}, metricsCmd)
+}
+
+var metricsCmd = &cobra.Command{
+	Use:   "Metrics",
+	Short: "Init Metrics",
+	Long:  `加载Metrics模块之后，可以通过 metricsmodule.Middleware 采集 HTTP 指标，并以 Prometheus 的格式暴露 HTTP、数据库、Redis、缓存和限流的指标`,
+}
+
+// DefaultPath 默认的指标路径
+const DefaultPath = "/metrics"
+
+// DefaultAddr 默认在单独的端口上只监听本机地址, 与业务服务的端口分开
+const DefaultAddr = "127.0.0.1:9464"
+
+// 指标名称的前缀
+const namespace = "gina"
+
+var (
+	// 框架自身的指标, 与 prometheus.DefaultRegisterer 分开, 避免和业务注册的指标冲突
+	registry     = prometheus.NewRegistry()
+	metricsPath  = DefaultPath
+	metricsAddr  string // 为空时由 Middleware 在业务服务的端口上暴露指标
+	metricsToken string // 访问令牌, 为空时业务服务的端口上只允许本机访问
+)
+
+type metricsModule struct{}
+
+func (self *metricsModule) Name() string {
+	return "Metrics"
+}
+
+// 数据库和 Redis 的指标在采集时读取, 不需要依赖对应的模块
+func (self *metricsModule) DependsOn() []string {
+	return []string{gina.ModuleName}
+}
+
+func (self *metricsModule) Init() error {
+	viper.SetDefault("Metrics.Path", DefaultPath)
+	viper.SetDefault("Metrics.Addr", DefaultAddr)
+	metricsPath = viper.GetString("Metrics.Path")
+	metricsAddr = viper.GetString("Metrics.Addr")
+	metricsToken = viper.GetString("Metrics.Token")
+
+	switch {
+	case metricsAddr != "":
+		if metricsToken == "" && !ginahelper.IsLoopback(metricsAddr) {
+			console.Echo.Warnf("⚠️ 警告: Metrics.Addr 不是本机地址且没有配置 Metrics.Token, 请确认 %s 不对外开放\n", metricsAddr)
+		}
+		gina.Register(&metricsServer{})
+		console.Echo.Infof("✅ 提示: Metrics模块加载成功, 指标地址为 %s%s\n", metricsAddr, metricsPath)
+	case metricsToken == "":
+		console.Echo.Infof("✅ 提示: Metrics模块加载成功, 通过 `metricsmodule.Middleware` 在服务的 %s 上暴露指标, 没有配置 Metrics.Token, 只允许本机访问\n", metricsPath)
+	default:
+		console.Echo.Infof("✅ 提示: Metrics模块加载成功, 通过 `metricsmodule.Middleware` 在服务的 %s 上暴露指标, 需要携带 Metrics.Token 访问\n", metricsPath)
+	}
+
+	return nil
+}
+
+func (self *metricsModule) ConfigSchema() []gina.ConfigField {
+	return []gina.ConfigField{
+		{Key: "Metrics.Path", Type: gina.ConfigTypeString},
+		{Key: "Metrics.Addr", Type: gina.ConfigTypeString},
+		{Key: "Metrics.Token", Type: gina.ConfigTypeString},
+	}
+}
+
+func (self *metricsModule) Close() error {
+	return nil
+}
+
+// Handler Prometheus 文本格式的指标, 包含框架的指标和注册到 prometheus.DefaultRegisterer 的指标
+func Handler() http.Handler {
+	return promhttp.HandlerFor(prometheus.Gatherers{registry, prometheus.DefaultGatherer}, promhttp.HandlerOpts{})
+}
+
+// Register 注册自定义的指标, 与框架的指标一起暴露, 也可以直接使用 prometheus.DefaultRegisterer
+func Register(collectors ...prometheus.Collector) error {
+	for _, collector := range collectors {
+		if err := registry.Register(collector); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 默认在单独的端口上暴露指标, 避免通过业务服务的端口对外暴露
+type metricsServer struct {
+	*gina.IServer
+
+	httpModule httpmodule.IHttp
+}
+
+func (self *metricsServer) OnStart() error {
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+	engine.GET(metricsPath, auth(metricsToken), gin.WrapH(Handler()))
+
+	self.httpModule.Init(self, metricsAddr, 0, engine)
+	return self.httpModule.Start()
+}
+
+func (self *metricsServer) OnStop(ctx context.Context) error {
+	return self.httpModule.Stop(ctx)
+}
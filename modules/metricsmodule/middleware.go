@@ -0,0 +1,70 @@
+package metricsmodule
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// 未匹配到路由的请求统一使用该路由, 避免按原始路径统计导致指标数量失控
+const unmatchedRoute = "unmatched"
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP 请求数",
+	}, []string{"method", "route", "code"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP 请求的处理时间",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "code"})
+
+	httpInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "正在处理的 HTTP 请求数",
+	})
+)
+
+// Middleware 按路由模板和状态码统计请求数和处理时间, 如 /api/v1/user/:id
+// Metrics.Addr 配置为空时同时在 Metrics.Path 上暴露指标, 需要放在鉴权等中间件之前
+// 此时配置了 Metrics.Token 的需要携带令牌访问, 否则只允许本机访问
+func Middleware() gin.HandlerFunc {
+	handler := Handler()
+
+	return func(ctx *gin.Context) {
+		if metricsAddr == "" && ctx.Request.URL.Path == metricsPath {
+			if authorized(ctx, metricsToken, true) {
+				// 指标路径没有注册路由, gin 已经预设了 404, promhttp 不会主动写入状态码
+				ctx.Status(http.StatusOK)
+				handler.ServeHTTP(ctx.Writer, ctx.Request)
+				ctx.Abort()
+			}
+			return
+		}
+
+		start := time.Now()
+		httpInFlight.Inc()
+		defer httpInFlight.Dec()
+
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		code := strconv.Itoa(ctx.Writer.Status())
+		httpRequests.WithLabelValues(ctx.Request.Method, route, code).Inc()
+		httpDuration.WithLabelValues(ctx.Request.Method, route, code).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,100 @@
+package metricsmodule
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 在业务服务的端口上暴露指标时, 没有令牌只允许本机访问
+func TestMiddlewareAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	metricsAddr, metricsPath = "", DefaultPath
+	t.Cleanup(func() { metricsToken = "" })
+
+	tests := []struct {
+		name   string
+		token  string
+		remote string
+		header string
+		want   int
+	}{
+		{name: "本机访问", remote: "127.0.0.1:50000", want: http.StatusOK},
+		{name: "IPv6 本机访问", remote: "[::1]:50000", want: http.StatusOK},
+		{name: "外部访问", remote: "10.0.0.1:50000", want: http.StatusForbidden},
+		{name: "携带正确的令牌", token: "secret", remote: "10.0.0.1:50000", header: "Bearer secret", want: http.StatusOK},
+		{name: "令牌错误", token: "secret", remote: "10.0.0.1:50000", header: "Bearer wrong", want: http.StatusUnauthorized},
+		{name: "配置了令牌时本机也需要携带", token: "secret", remote: "127.0.0.1:50000", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metricsToken = tt.token
+			engine := gin.New()
+			engine.Use(Middleware())
+			engine.GET("/ping", func(ctx *gin.Context) { ctx.String(http.StatusOK, "pong") })
+
+			req := httptest.NewRequest(http.MethodGet, DefaultPath, nil)
+			req.RemoteAddr = tt.remote
+			req.Header.Set("X-Forwarded-For", "127.0.0.1")
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("状态码为 %d, 应该为 %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddlewareRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	metricsAddr, metricsPath, metricsToken = "", DefaultPath, ""
+
+	engine := gin.New()
+	engine.Use(Middleware())
+	engine.GET("/user/:id", func(ctx *gin.Context) { ctx.Status(http.StatusNoContent) })
+	for _, path := range []string{"/user/1", "/user/2", "/missing"} {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	tests := []struct {
+		route, code string
+		want        float64
+	}{
+		{"/user/:id", "204", 2},
+		{unmatchedRoute, "404", 1},
+	}
+	for _, tt := range tests {
+		if got := counterValue(t, tt.route, tt.code); got != tt.want {
+			t.Fatalf("路由 %s 状态码 %s 的请求数为 %v, 应该为 %v", tt.route, tt.code, got, tt.want)
+		}
+	}
+}
+
+func counterValue(t *testing.T, route, code string) float64 {
+	t.Helper()
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != "gina_http_requests_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["route"] == route && labels["code"] == code {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+
+	return 0
+}
@@ -2,6 +2,8 @@ package mongomodule
 
 import (
 	"context"
+	"fmt"
+
 	"github.com/soryetong/greasyx/console"
 	"github.com/soryetong/greasyx/gina"
 	"github.com/spf13/cobra"
@@ -11,35 +13,73 @@ import (
 )
 
 func init() {
-	console.Append(mongoCmd)
+	console.AppendModule(&mongoModule{}, mongoCmd)
 }
 
 var mongoCmd = &cobra.Command{
 	Use:   "MongoDB",
 	Short: "Init MongoDB",
 	Long:  `加载MongoDB模块之后，可以通过 gina.Mdb 进行数据操作`,
-	Run: func(cmd *cobra.Command, args []string) {
-		url := viper.GetString("Mongo.Url")
-		if url == "" {
-			console.Echo.Fatalln("❌ 错误: 你正在加载MongoDB模块，但是你未配置Mongo.Url，请先添加配置\n")
-		}
+}
+
+type mongoModule struct{}
+
+func (self *mongoModule) Name() string {
+	return "MongoDB"
+}
+
+func (self *mongoModule) DependsOn() []string {
+	return []string{gina.ModuleName}
+}
+
+func (self *mongoModule) Init() error {
+	url := viper.GetString("Mongo.Url")
+	if url == "" {
+		return fmt.Errorf("你正在加载MongoDB模块，但是你未配置Mongo.Url，请先添加配置")
+	}
+
+	return initClient(url)
+}
 
-		initClient(url)
-	},
+func (self *mongoModule) ConfigSchema() []gina.ConfigField {
+	return []gina.ConfigField{
+		{Key: "Mongo.Url", Type: gina.ConfigTypeString, Required: true},
+	}
 }
 
-func initClient(url string) {
+// 连接在创建时已经注册了释放函数
+func (self *mongoModule) Close() error {
+	return nil
+}
+
+// New 创建 MongoDB 连接并检查连通性, 不会赋值给 gina.Mdb, 失败时返回错误而不是退出程序
+func New(url string) (*mongo.Client, error) {
 	clientOptions := options.Client().ApplyURI(url)
 	client, err := mongo.Connect(context.TODO(), clientOptions)
 	if err != nil {
-		console.Echo.Fatalf("❌ 错误: MongoDB连接失败: %s\n", err)
+		return nil, fmt.Errorf("MongoDB连接失败: %w", err)
 	}
 
 	// 检查连接
 	if err = client.Ping(context.TODO(), nil); err != nil {
-		console.Echo.Fatalf("❌ 错误: MongoDB连接失败: %s\n", err)
+		_ = client.Disconnect(context.TODO())
+		return nil, fmt.Errorf("MongoDB连接失败: %w", err)
+	}
+
+	return client, nil
+}
+
+func initClient(url string) error {
+	client, err := New(url)
+	if err != nil {
+		return err
 	}
 
 	gina.Mdb = client
+	console.RegisterCloser("MongoDB", func(ctx context.Context) error {
+		return client.Disconnect(ctx)
+	})
 	console.Echo.Info("✅ 提示: Mongo模块加载成功, 你可以使用 `gina.Mdb` 进行数据操作\n")
+
+	return nil
 }
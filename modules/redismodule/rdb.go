@@ -2,56 +2,118 @@ package redismodule
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/go-redis/redis/v8"
 	"github.com/soryetong/greasyx/console"
 	"github.com/soryetong/greasyx/gina"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"strings"
-	"time"
 )
 
 func init() {
-	console.Append(redisCmd)
+	console.AppendModule(&redisModule{}, redisCmd)
 }
 
 var redisCmd = &cobra.Command{
 	Use:   "Redis",
 	Short: "Init Redis",
 	Long:  `加载Redis模块之后，可以通过 gina.Rdb 进行数据操作`,
-	Run: func(cmd *cobra.Command, args []string) {
-		addr := viper.GetString("Redis.Addr")
-		if addr == "" {
-			console.Echo.Fatalf("❌ 错误: 你正在加载Redis模块，但是你未配置Redis.Addr，请先添加配置\n")
-		}
+}
+
+type redisModule struct{}
 
-		viper.SetDefault("Redis.IsCluster", false)
-		viper.SetDefault("Redis.Db", 0)
-		gina.Rdb = initRedis(
-			addr,
-			viper.GetString("Redis.Password"),
-			viper.GetInt("Redis.Db"),
-			viper.GetBool("Redis.IsCluster"),
-		)
-		console.Echo.Infof("✅ 提示: Redis模块加载成功, 你可以使用 `gina.Rdb` 进行数据操作\n")
-	},
+func (self *redisModule) Name() string {
+	return "Redis"
 }
 
-func initRedis(addr, password string, db int, cluster bool) redis.Cmdable {
-	if cluster {
-		return initCluster(addr, password, db)
+func (self *redisModule) DependsOn() []string {
+	return []string{gina.ModuleName}
+}
+
+func (self *redisModule) Init() error {
+	addr := viper.GetString("Redis.Addr")
+	if addr == "" {
+		return fmt.Errorf("你正在加载Redis模块，但是你未配置Redis.Addr，请先添加配置")
+	}
+
+	viper.SetDefault("Redis.IsCluster", false)
+	viper.SetDefault("Redis.Db", 0)
+	client, err := New(&Options{
+		Addr:      addr,
+		Password:  viper.GetString("Redis.Password"),
+		Db:        viper.GetInt("Redis.Db"),
+		IsCluster: viper.GetBool("Redis.IsCluster"),
+	})
+	if err != nil {
+		return err
+	}
+
+	console.RegisterCloser("Redis", func(ctx context.Context) error {
+		return client.Close()
+	})
+	gina.Rdb = client
+	console.Echo.Infof("✅ 提示: Redis模块加载成功, 你可以使用 `gina.Rdb` 进行数据操作\n")
+
+	return nil
+}
+
+func (self *redisModule) ConfigSchema() []gina.ConfigField {
+	return []gina.ConfigField{
+		{Key: "Redis.Addr", Type: gina.ConfigTypeString, Required: true},
+		{Key: "Redis.Password", Type: gina.ConfigTypeString},
+		{Key: "Redis.Db", Type: gina.ConfigTypeInt},
+		{Key: "Redis.IsCluster", Type: gina.ConfigTypeBool},
+	}
+}
+
+// 连接在创建时已经注册了释放函数
+func (self *redisModule) Close() error {
+	return nil
+}
+
+// Options Redis 连接配置, 对应配置文件中的 Redis 节点
+type Options struct {
+	Addr      string // 集群模式下多个地址使用 , 分隔, 包含 / 时使用 unix socket 连接
+	Password  string
+	Db        int
+	IsCluster bool
+}
+
+// New 创建 Redis 连接并检查连通性, 不会赋值给 gina.Rdb, 失败时返回错误而不是退出程序
+func New(opts *Options) (redis.UniversalClient, error) {
+	if opts.Addr == "" {
+		return nil, fmt.Errorf("Redis 地址不能为空")
+	}
+
+	var client redis.UniversalClient
+	if opts.IsCluster {
+		client = newCluster(opts.Addr, opts.Password)
 	} else {
-		return initSingleNode(addr, password, db)
+		client = newSingleNode(opts.Addr, opts.Password, opts.Db)
+	}
+
+	if _, err := client.Ping(context.Background()).Result(); err != nil {
+		_ = client.Close()
+		if opts.IsCluster {
+			return nil, fmt.Errorf("Redis集群连接失败: %w", err)
+		}
+		return nil, fmt.Errorf("Redis连接失败: %w", err)
 	}
+	client.AddHook(traceHook{})
+
+	return client, nil
 }
 
-func initSingleNode(addr, password string, db int) redis.Cmdable {
+func newSingleNode(addr, password string, db int) *redis.Client {
 	networkType := "tcp"
 	if strings.Contains(addr, "/") {
 		networkType = "unix"
 	}
 
-	rdbClient := redis.NewClient(&redis.Options{
+	return redis.NewClient(&redis.Options{
 		Network:  networkType,
 		Addr:     addr,
 		Password: password,
@@ -68,17 +130,10 @@ func initSingleNode(addr, password string, db int) redis.Cmdable {
 		MinRetryBackoff: 8 * time.Millisecond,   // 每次计算重试间隔时间的下限，默认8毫秒，-1表示取消间隔
 		MaxRetryBackoff: 512 * time.Millisecond, // 每次计算重试间隔时间的上限，默认512毫秒，-1表示取消间隔
 	})
-
-	_, err := rdbClient.Ping(context.Background()).Result()
-	if err != nil {
-		console.Echo.Fatalf("❌ 错误: Redis连接失败: %s\n", err)
-	}
-
-	return rdbClient
 }
 
-func initCluster(addr, password string, db int) redis.Cmdable {
-	rdbClient := redis.NewClusterClient(&redis.ClusterOptions{
+func newCluster(addr, password string) *redis.ClusterClient {
+	return redis.NewClusterClient(&redis.ClusterOptions{
 		Addrs:    strings.Split(addr, ","),
 		Password: password,
 
@@ -105,11 +160,4 @@ func initCluster(addr, password string, db int) redis.Cmdable {
 		// 默认false，置为true则ReadOnly自动为true，表示在处理只读命令时，可以在一个slot对应的主节点和所有从节点中随机选取一个节点来读数据
 		RouteByLatency: true,
 	})
-
-	_, err := rdbClient.Ping(context.Background()).Result()
-	if err != nil {
-		console.Echo.Fatalf("❌ 错误: Redis集群连接失败: %s\n", err)
-	}
-
-	return rdbClient
 }
@@ -0,0 +1,63 @@
+package redismodule
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/soryetong/greasyx/libs/ginatrace"
+)
+
+// 为 ctx 中有 span 的命令创建子 span, 后台任务等没有链路信息的命令不记录
+type traceHook struct{}
+
+type spanKey struct{}
+
+func (self traceHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return startSpan(ctx, "redis "+cmd.Name(), map[string]any{"db.operation.name": cmd.Name()}), nil
+}
+
+func (self traceHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	endSpan(ctx, cmd.Err())
+	return nil
+}
+
+func (self traceHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return startSpan(ctx, "redis pipeline", map[string]any{"db.operation.batch.size": len(cmds)}), nil
+}
+
+func (self traceHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmdErr := cmd.Err(); cmdErr != nil && !errors.Is(cmdErr, redis.Nil) {
+			err = cmdErr
+			break
+		}
+	}
+	endSpan(ctx, err)
+
+	return nil
+}
+
+func startSpan(ctx context.Context, name string, attrs map[string]any) context.Context {
+	if ginatrace.SpanFromContext(ctx) == nil {
+		return ctx
+	}
+
+	attrs["db.system"] = "redis"
+	ctx, span := ginatrace.Start(ctx, name, ginatrace.WithKind(ginatrace.SpanKindClient), ginatrace.WithAttributes(attrs))
+
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// key 不存在时返回的 redis.Nil 不是错误
+func endSpan(ctx context.Context, err error) {
+	span, ok := ctx.Value(spanKey{}).(*ginatrace.Span)
+	if !ok {
+		return
+	}
+	if err != nil && !errors.Is(err, redis.Nil) {
+		span.RecordError(err)
+	}
+	span.End()
+}
@@ -0,0 +1,93 @@
+package tracemodule
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/soryetong/greasyx/console"
+	"github.com/soryetong/greasyx/gina"
+	"github.com/soryetong/greasyx/libs/ginatrace"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	console.AppendModule(&traceModule{}, traceCmd)
+}
+
+var traceCmd = &cobra.Command{
+	Use:   "Trace",
+	Short: "Init Trace",
+	Long:  `加载Trace模块之后，请求、数据库、Redis 和 DoRequest 的 span 会导出到 stdout 或 OTLP/HTTP 收集器`,
+}
+
+// 支持的导出方式
+const (
+	ExporterNone   = ""
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+type traceModule struct{}
+
+func (self *traceModule) Name() string {
+	return "Trace"
+}
+
+func (self *traceModule) DependsOn() []string {
+	return []string{gina.ModuleName}
+}
+
+func (self *traceModule) Init() error {
+	viper.SetDefault("Trace.SampleRatio", 1) // 只对链路的第一个 span 生效, 超出 0 到 1 时取边界值
+	viper.SetDefault("Trace.ServiceName", viper.GetString("App.Name"))
+
+	ginatrace.SetSampleRatio(viper.GetFloat64("Trace.SampleRatio"))
+
+	serviceName := viper.GetString("Trace.ServiceName")
+	if serviceName == "" {
+		serviceName = "greasyx"
+	}
+
+	name := viper.GetString("Trace.Exporter")
+	var exporter ginatrace.Exporter
+	switch name {
+	case ExporterNone:
+		// 不导出时仍然生成 trace_id 并通过 traceparent 传递给下游服务
+		console.Echo.Infof("✅ 提示: Trace模块加载成功, 未配置 Trace.Exporter, span 不会被导出\n")
+		return nil
+	case ExporterStdout:
+		exporter = ginatrace.NewStdoutExporter(os.Stdout)
+	case ExporterOTLP:
+		exporter = ginatrace.NewOTLPExporter(ginatrace.OTLPConfig{
+			Endpoint:    viper.GetString("Trace.Endpoint"),
+			Headers:     viper.GetStringMapString("Trace.Headers"),
+			ServiceName: serviceName,
+		})
+	default:
+		return fmt.Errorf("不支持的 Trace.Exporter: %s", name)
+	}
+
+	ginatrace.SetExporter(exporter)
+	console.RegisterCloser("Trace", func(ctx context.Context) error {
+		return ginatrace.Shutdown(ctx)
+	})
+	console.Echo.Infof("✅ 提示: Trace模块加载成功, span 通过 %s 导出, 服务名称为 %s\n", name, serviceName)
+
+	return nil
+}
+
+func (self *traceModule) ConfigSchema() []gina.ConfigField {
+	return []gina.ConfigField{
+		{Key: "Trace.Exporter", Type: gina.ConfigTypeString, Enum: []string{ExporterStdout, ExporterOTLP}},
+		{Key: "Trace.Endpoint", Type: gina.ConfigTypeString},
+		{Key: "Trace.Headers", Type: gina.ConfigTypeMap},
+		{Key: "Trace.SampleRatio", Type: gina.ConfigTypeFloat},
+		{Key: "Trace.ServiceName", Type: gina.ConfigTypeString},
+	}
+}
+
+func (self *traceModule) Close() error {
+	return nil
+}
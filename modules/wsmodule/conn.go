@@ -0,0 +1,161 @@
+package wsmodule
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/soryetong/greasyx/ginahelper"
+)
+
+var (
+	// ErrClosed 连接已关闭
+	ErrClosed = errors.New("连接已关闭")
+	// ErrSlowConsumer 发送队列已满, 客户端接收过慢, 连接会被关闭
+	ErrSlowConsumer = errors.New("发送队列已满")
+)
+
+// 每个连接等待发送的消息的最大数量
+const sendQueueSize = 256
+
+// Conn 一个 WebSocket 连接, 同一个用户可以有多个连接, 如多个浏览器标签页
+type Conn struct {
+	ws     *websocket.Conn
+	userId int64
+	claims map[string]interface{}
+	rooms  map[string]struct{} // 由 hub.mu 保护
+
+	send      chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+	closeCode int
+	closeText string
+}
+
+func newConn(ws *websocket.Conn, userId int64, claims map[string]interface{}) *Conn {
+	return &Conn{
+		ws:        ws,
+		userId:    userId,
+		claims:    claims,
+		rooms:     make(map[string]struct{}),
+		send:      make(chan []byte, sendQueueSize),
+		done:      make(chan struct{}),
+		closeCode: websocket.CloseNormalClosure,
+	}
+}
+
+// UserId Token 中的用户 ID
+func (self *Conn) UserId() int64 {
+	return self.userId
+}
+
+// GetTokenData 读取 Token 中的字段, 与 ginaauth.GetTokenData 一致
+func GetTokenData[T ginahelper.MapSupportedTypes](conn *Conn, key string) T {
+	return ginahelper.GetMapSpecificValue[T](conn.claims, key)
+}
+
+// Join 加入房间, 之后可以通过 SendToRoom 收到房间的消息
+func (self *Conn) Join(room string) {
+	hub.join(self, room)
+}
+
+// Leave 离开房间
+func (self *Conn) Leave(room string) {
+	hub.leave(self, room)
+}
+
+// Rooms 已加入的房间
+func (self *Conn) Rooms() []string {
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+
+	rooms := make([]string, 0, len(self.rooms))
+	for room := range self.rooms {
+		rooms = append(rooms, room)
+	}
+	slices.Sort(rooms)
+
+	return rooms
+}
+
+// Send 只发送给当前连接, 不会阻塞, 发送队列已满时关闭连接
+func (self *Conn) Send(data []byte) error {
+	select {
+	case <-self.done:
+		return ErrClosed
+	default:
+	}
+
+	select {
+	case self.send <- data:
+		return nil
+	default:
+		self.closeWith(websocket.ClosePolicyViolation, "slow consumer")
+		return ErrSlowConsumer
+	}
+}
+
+// Close 发送关闭帧后断开连接
+func (self *Conn) Close() {
+	self.closeWith(websocket.CloseNormalClosure, "")
+}
+
+func (self *Conn) closeWith(code int, text string) {
+	self.closeOnce.Do(func() {
+		self.closeCode, self.closeText = code, text
+		close(self.done)
+	})
+}
+
+// 读取客户端的消息, 收到任何消息或 pong 都会延长读取的截止时间, 超时后断开连接
+func (self *Conn) readPump(onMessage MessageFunc) {
+	self.ws.SetReadLimit(config.maxMessageSize)
+	_ = self.ws.SetReadDeadline(time.Now().Add(config.pongWait))
+	self.ws.SetPongHandler(func(string) error {
+		return self.ws.SetReadDeadline(time.Now().Add(config.pongWait))
+	})
+
+	for {
+		_, data, err := self.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		_ = self.ws.SetReadDeadline(time.Now().Add(config.pongWait))
+		if onMessage != nil {
+			onMessage(self, data)
+		}
+	}
+}
+
+// 所有写操作都在这个协程中执行, 定时发送 ping, 关闭时发送关闭帧
+func (self *Conn) writePump() {
+	ticker := time.NewTicker(config.pingInterval)
+	defer func() {
+		ticker.Stop()
+		_ = self.ws.Close()
+	}()
+
+	for {
+		select {
+		case data := <-self.send:
+			_ = self.ws.SetWriteDeadline(time.Now().Add(config.writeTimeout))
+			if err := self.ws.WriteMessage(websocket.TextMessage, data); err != nil {
+				self.closeWith(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-ticker.C:
+			if err := self.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(config.writeTimeout)); err != nil {
+				self.closeWith(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-self.done:
+			if self.closeCode != websocket.CloseAbnormalClosure {
+				message := websocket.FormatCloseMessage(self.closeCode, self.closeText)
+				_ = self.ws.WriteControl(websocket.CloseMessage, message, time.Now().Add(config.writeTimeout))
+			}
+			return
+		}
+	}
+}
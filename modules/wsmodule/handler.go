@@ -0,0 +1,149 @@
+package wsmodule
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/soryetong/greasyx/gina"
+	"github.com/soryetong/greasyx/ginahelper"
+	"github.com/soryetong/greasyx/libs/ginaauth"
+	"github.com/soryetong/greasyx/libs/ginaerror"
+)
+
+// MessageFunc 处理客户端发送的消息, 在连接的读取协程中按顺序调用
+type MessageFunc func(conn *Conn, data []byte)
+
+// HandlerOption Handler 的可选配置
+type HandlerOption func(*handlerOptions)
+
+type handlerOptions struct {
+	onConnect func(conn *Conn)
+	onMessage MessageFunc
+	onClose   func(conn *Conn)
+}
+
+// WithOnConnect 连接建立后调用, 一般在这里加入房间
+func WithOnConnect(fn func(conn *Conn)) HandlerOption {
+	return func(o *handlerOptions) {
+		o.onConnect = fn
+	}
+}
+
+// WithOnMessage 收到客户端的消息时调用
+func WithOnMessage(fn MessageFunc) HandlerOption {
+	return func(o *handlerOptions) {
+		o.onMessage = fn
+	}
+}
+
+// WithOnClose 连接断开后调用, 此时已经离开了所有房间
+func WithOnClose(fn func(conn *Conn)) HandlerOption {
+	return func(o *handlerOptions) {
+		o.onClose = fn
+	}
+}
+
+// Handler 校验 Token 后升级为 WebSocket 连接, 并按 Token 中的 id 保存到 Hub 中
+// Token 依次从 Jwt 中间件、查询参数 token、Sec-WebSocket-Protocol 和 Authorization 中读取
+// 浏览器无法设置请求头, 可以使用 new WebSocket(url, ["bearer", token]), 服务端会返回 bearer 作为协议
+func Handler(opts ...HandlerOption) gin.HandlerFunc {
+	options := handlerOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return func(ctx *gin.Context) {
+		if hub == nil {
+			gina.Fail(ctx, ginaerror.ServerError, ErrNotLoaded.Error())
+			ctx.Abort()
+			return
+		}
+
+		claims, protocol := authenticate(ctx)
+		userId := ginahelper.GetMapSpecificValue[int64](claims, "id")
+		if userId == 0 {
+			gina.Fail(ctx, ginaerror.NeedLogin)
+			ctx.Abort()
+			return
+		}
+
+		var header http.Header
+		if protocol != "" {
+			header = http.Header{"Sec-Websocket-Protocol": {protocol}}
+		}
+		// 升级失败时 Upgrade 已经返回了错误响应
+		ws, err := upgrader.Upgrade(ctx.Writer, ctx.Request, header)
+		if err != nil {
+			ctx.Abort()
+			return
+		}
+
+		conn := newConn(ws, userId, claims)
+		hub.add(conn)
+		if options.onConnect != nil {
+			options.onConnect(conn)
+		}
+
+		go conn.writePump()
+		conn.readPump(options.onMessage)
+		// 读取结束时连接已经断开, 不需要再发送关闭帧
+		conn.closeWith(websocket.CloseAbnormalClosure, "")
+		hub.remove(conn)
+		if options.onClose != nil {
+			options.onClose(conn)
+		}
+	}
+}
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: checkOrigin,
+}
+
+// 未配置 WebSocket.AllowOrigins 时只允许同源的连接, 配置为 * 时允许所有来源
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if len(config.allowOrigins) == 0 {
+		return strings.TrimPrefix(strings.TrimPrefix(origin, "https://"), "http://") == r.Host
+	}
+
+	return slices.Contains(config.allowOrigins, "*") || slices.Contains(config.allowOrigins, origin)
+}
+
+// 返回 Token 中的数据和需要返回给客户端的 Sec-WebSocket-Protocol
+func authenticate(ctx *gin.Context) (map[string]interface{}, string) {
+	if claims, ok := ctx.Value("claims").(map[string]interface{}); ok {
+		return claims, ""
+	}
+
+	if token := ctx.Query("token"); token != "" {
+		claims, _ := ginaauth.ParseJwtToken(token)
+		return claims, ""
+	}
+
+	// 客户端传了多个协议时, 返回 Token 以外的第一个协议, 否则浏览器会因为协议不匹配而断开连接
+	protocols := websocket.Subprotocols(ctx.Request)
+	for i, protocol := range protocols {
+		claims, err := ginaauth.ParseJwtToken(protocol)
+		if err != nil {
+			continue
+		}
+		others := slices.Delete(slices.Clone(protocols), i, i+1)
+		if len(others) > 0 {
+			return claims, others[0]
+		}
+		return claims, protocol
+	}
+
+	if token := ctx.GetHeader("Authorization"); strings.HasPrefix(token, "Bearer ") {
+		claims, _ := ginaauth.ParseJwtToken(token[7:])
+		return claims, ""
+	}
+
+	return nil, ""
+}
@@ -0,0 +1,238 @@
+package wsmodule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/soryetong/greasyx/console"
+	"github.com/soryetong/greasyx/gina"
+	"go.uber.org/zap"
+)
+
+// ErrNotLoaded 未加载 WebSocket 模块时推送消息返回的错误
+var ErrNotLoaded = errors.New("WebSocket 模块未加载")
+
+// 推送的目标
+const (
+	targetUser = "user"
+	targetRoom = "room"
+	targetAll  = "all"
+)
+
+// 通过 Redis 发布的消息, 每个实例收到后推送给本地的连接
+type envelope struct {
+	Target string `json:"target"`
+	Key    string `json:"key,omitempty"` // 用户 ID 或房间名
+	Data   []byte `json:"data"`
+}
+
+// Hub 管理当前实例上的连接, 按用户 ID 和房间索引, 作为服务注册, 停止时关闭所有连接
+type Hub struct {
+	*gina.IServer
+
+	subscriber subscriber
+	mu         sync.RWMutex
+	conns      map[*Conn]struct{}
+	users      map[int64]map[*Conn]struct{}
+	rooms      map[string]map[*Conn]struct{}
+
+	quit    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func newHub(subscriber subscriber) *Hub {
+	return &Hub{
+		subscriber: subscriber,
+		conns:      make(map[*Conn]struct{}),
+		users:      make(map[int64]map[*Conn]struct{}),
+		rooms:      make(map[string]map[*Conn]struct{}),
+		quit:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+}
+
+// OnStart 订阅 Redis 频道并把消息推送给本地的连接, 阻塞直到 OnStop 被调用, 断线后会自动重新订阅
+func (self *Hub) OnStart() error {
+	defer close(self.stopped)
+
+	ctx := context.Background()
+	pubsub := self.subscriber.Subscribe(ctx, config.channel)
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return err
+	}
+	messages := pubsub.Channel()
+	gina.Started(self)
+
+	for {
+		select {
+		case <-self.quit:
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			var env envelope
+			if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+				gina.Log.Error("[WebSocket.Subscribe] 消息格式错误, 已丢弃", zap.String("payload", msg.Payload), zap.Error(err))
+				continue
+			}
+			self.deliver(env)
+		}
+	}
+}
+
+// OnStop 取消订阅并以 1001 关闭所有连接, 客户端收到后可以重新连接到其他实例
+func (self *Hub) OnStop(ctx context.Context) error {
+	var err error
+	self.once.Do(func() {
+		close(self.quit)
+		select {
+		case <-self.stopped:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+
+		self.mu.RLock()
+		conns := make([]*Conn, 0, len(self.conns))
+		for conn := range self.conns {
+			conns = append(conns, conn)
+		}
+		self.mu.RUnlock()
+		for _, conn := range conns {
+			conn.closeWith(websocket.CloseGoingAway, "server shutdown")
+		}
+		console.Echo.Infof("✅ 提示: WebSocket 已关闭 %d 个连接\n", len(conns))
+	})
+
+	return err
+}
+
+func (self *Hub) add(conn *Conn) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	self.conns[conn] = struct{}{}
+	if self.users[conn.userId] == nil {
+		self.users[conn.userId] = make(map[*Conn]struct{})
+	}
+	self.users[conn.userId][conn] = struct{}{}
+}
+
+func (self *Hub) remove(conn *Conn) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	delete(self.conns, conn)
+	if conns := self.users[conn.userId]; conns != nil {
+		delete(conns, conn)
+		if len(conns) == 0 {
+			delete(self.users, conn.userId)
+		}
+	}
+	for room := range conn.rooms {
+		self.leaveLocked(conn, room)
+	}
+}
+
+func (self *Hub) join(conn *Conn, room string) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if _, ok := self.conns[conn]; !ok {
+		return
+	}
+	if self.rooms[room] == nil {
+		self.rooms[room] = make(map[*Conn]struct{})
+	}
+	self.rooms[room][conn] = struct{}{}
+	conn.rooms[room] = struct{}{}
+}
+
+func (self *Hub) leave(conn *Conn, room string) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	self.leaveLocked(conn, room)
+}
+
+func (self *Hub) leaveLocked(conn *Conn, room string) {
+	delete(conn.rooms, room)
+	if conns := self.rooms[room]; conns != nil {
+		delete(conns, conn)
+		if len(conns) == 0 {
+			delete(self.rooms, room)
+		}
+	}
+}
+
+// 推送给本地的连接, 先复制连接列表, 避免发送时持有锁
+func (self *Hub) deliver(env envelope) {
+	self.mu.RLock()
+	var targets []*Conn
+	switch env.Target {
+	case targetUser:
+		userId, _ := strconv.ParseInt(env.Key, 10, 64)
+		for conn := range self.users[userId] {
+			targets = append(targets, conn)
+		}
+	case targetRoom:
+		for conn := range self.rooms[env.Key] {
+			targets = append(targets, conn)
+		}
+	case targetAll:
+		for conn := range self.conns {
+			targets = append(targets, conn)
+		}
+	}
+	self.mu.RUnlock()
+
+	for _, conn := range targets {
+		_ = conn.Send(env.Data)
+	}
+}
+
+func publish(ctx context.Context, env envelope) error {
+	if hub == nil {
+		return ErrNotLoaded
+	}
+
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+
+	return gina.Rdb.Publish(ctx, config.channel, payload).Err()
+}
+
+// SendToUser 推送给用户在所有实例上的连接
+func SendToUser(ctx context.Context, userId int64, data []byte) error {
+	return publish(ctx, envelope{Target: targetUser, Key: strconv.FormatInt(userId, 10), Data: data})
+}
+
+// SendToRoom 推送给所有实例上加入了房间的连接
+func SendToRoom(ctx context.Context, room string, data []byte) error {
+	return publish(ctx, envelope{Target: targetRoom, Key: room, Data: data})
+}
+
+// Broadcast 推送给所有实例上的连接
+func Broadcast(ctx context.Context, data []byte) error {
+	return publish(ctx, envelope{Target: targetAll, Data: data})
+}
+
+// Count 当前实例上的连接数和在线用户数
+func Count() (conns int, users int) {
+	if hub == nil {
+		return 0, 0
+	}
+
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+
+	return len(hub.conns), len(hub.users)
+}
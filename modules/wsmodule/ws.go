@@ -0,0 +1,106 @@
+package wsmodule
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/soryetong/greasyx/console"
+	"github.com/soryetong/greasyx/gina"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	console.AppendModule(&wsModule{}, wsCmd)
+}
+
+var wsCmd = &cobra.Command{
+	Use:   "WebSocket",
+	Short: "Init WebSocket",
+	Long:  `加载WebSocket模块之后，可以通过 wsmodule.Handler 接收 WebSocket 连接，并通过 SendToUser、SendToRoom 和 Broadcast 向所有实例上的连接推送消息`,
+}
+
+// 默认配置
+const (
+	DefaultChannel        = "gina:ws"
+	DefaultPingInterval   = 30    // 秒
+	DefaultWriteTimeout   = 10    // 秒
+	DefaultMaxMessageSize = 65536 // 字节
+)
+
+type wsConfig struct {
+	channel        string
+	pingInterval   time.Duration
+	pongWait       time.Duration // 超过这个时间没有收到任何消息时断开连接
+	writeTimeout   time.Duration
+	maxMessageSize int64
+	allowOrigins   []string
+}
+
+var (
+	config wsConfig
+	hub    *Hub // 模块加载后才有值
+)
+
+type wsModule struct{}
+
+func (self *wsModule) Name() string {
+	return "WebSocket"
+}
+
+// 通过 Redis 的发布订阅把消息推送到所有实例
+func (self *wsModule) DependsOn() []string {
+	return []string{gina.ModuleName, "Redis"}
+}
+
+func (self *wsModule) Init() error {
+	viper.SetDefault("WebSocket.Channel", DefaultChannel)
+	viper.SetDefault("WebSocket.PingInterval", DefaultPingInterval)
+	viper.SetDefault("WebSocket.WriteTimeout", DefaultWriteTimeout)
+	viper.SetDefault("WebSocket.MaxMessageSize", DefaultMaxMessageSize)
+
+	config = wsConfig{
+		channel:        viper.GetString("WebSocket.Channel"),
+		pingInterval:   time.Duration(viper.GetInt("WebSocket.PingInterval")) * time.Second,
+		writeTimeout:   time.Duration(viper.GetInt("WebSocket.WriteTimeout")) * time.Second,
+		maxMessageSize: viper.GetInt64("WebSocket.MaxMessageSize"),
+		allowOrigins:   viper.GetStringSlice("WebSocket.AllowOrigins"),
+	}
+	if config.pingInterval <= 0 || config.writeTimeout <= 0 {
+		return fmt.Errorf("WebSocket.PingInterval 和 WebSocket.WriteTimeout 必须大于 0")
+	}
+	config.pongWait = config.pingInterval * 2
+
+	subscriber, ok := gina.Rdb.(subscriber)
+	if !ok {
+		return fmt.Errorf("当前的 Redis 客户端不支持发布订阅")
+	}
+
+	hub = newHub(subscriber)
+	gina.Register(hub)
+	console.Echo.Infof("✅ 提示: WebSocket模块加载成功, 通过 `wsmodule.Handler` 接收连接, 消息通过 Redis 频道 %s 广播\n", config.channel)
+
+	return nil
+}
+
+func (self *wsModule) ConfigSchema() []gina.ConfigField {
+	return []gina.ConfigField{
+		{Key: "WebSocket.Channel", Type: gina.ConfigTypeString},
+		{Key: "WebSocket.PingInterval", Type: gina.ConfigTypeInt},
+		{Key: "WebSocket.WriteTimeout", Type: gina.ConfigTypeInt},
+		{Key: "WebSocket.MaxMessageSize", Type: gina.ConfigTypeInt},
+		{Key: "WebSocket.AllowOrigins", Type: gina.ConfigTypeArray},
+	}
+}
+
+// 连接在服务停止时关闭
+func (self *wsModule) Close() error {
+	return nil
+}
+
+// 单机和集群模式的客户端都支持订阅
+type subscriber interface {
+	Subscribe(ctx context.Context, channels ...string) *redis.PubSub
+}
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 
 	"github.com/soryetong/greasyx/ginahelper"
+	"github.com/soryetong/greasyx/modules/httpmodule"
+	"github.com/spf13/viper"
 )
 
 type local struct{}
@@ -32,6 +34,28 @@ func (*local) Upload(file multipart.File, fileHeader *multipart.FileHeader, uplo
 	return &UploadRet{
 		Hash:     ginahelper.Md5Encode(filePath),
 		Filename: filename,
-		Url:      ginahelper.AssembleServerPath(filePath),
+		Url:      ginahelper.JoinServerPath(localBaseUrl(), filePath),
 	}, nil
 }
+
+// 优先使用 Oss.Url, 未配置时使用正在运行的服务的访问地址, 监听 App.Addr 的服务优先, 启用 TLS 时为 https
+func localBaseUrl() string {
+	if url := viper.GetString("Oss.Url"); url != "" {
+		return url
+	}
+
+	addr := viper.GetString("App.Addr")
+	instances := httpmodule.Instances()
+	for _, instance := range instances {
+		for _, listener := range instance.Listeners() {
+			if addr != "" && listener.Addr == addr {
+				return listener.URL()
+			}
+		}
+	}
+	if len(instances) > 0 {
+		return instances[0].URL()
+	}
+
+	return httpmodule.HTTP(addr).URL()
+}
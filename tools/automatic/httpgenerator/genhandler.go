@@ -45,31 +45,31 @@ if ginahelper.IsValidNumber(id) == false {
 	}
 {{end}}{{if .RequestType}}	var req {{.TypesPackageName}}.{{.RequestType}}
 	if err := ctx.ShouldBind(&req); err != nil {
-		gina.FailWithMessage(ctx, ginaerror.Trans(err))
+		gina.Error(ctx, ginaerror.TransError(ctx, err))
 		return
 	}
 
 	{{if .ResponseType}}resp, err := {{ .LogicPackageName}}.{{ .LogicName}}.{{ .LogicFuncName}}(ctx{{if .PathParam}}, id{{end}}, &req)
 	if err != nil {
-		gina.FailWithMessage(ctx, err.Error())
+		gina.Error(ctx, err)
 		return
 	}
 
 	gina.Success(ctx, resp)
 	{{else}}if err := {{ .LogicPackageName}}.{{ .LogicName}}.{{ .LogicFuncName}}(ctx{{if .PathParam}}, id{{end}}, &req); err != nil {
-		gina.FailWithMessage(ctx, err.Error())
+		gina.Error(ctx, err)
 		return
 	}
 
 	gina.Success(ctx, nil){{end}}{{else}}{{if .ResponseType}}resp, err := {{ .LogicPackageName}}.{{ .LogicName}}.{{ .LogicFuncName}}(ctx{{if .PathParam}}, id{{end}})
 	if err != nil {
-		gina.FailWithMessage(ctx, err.Error())
+		gina.Error(ctx, err)
 		return
 	}
 
 	gina.Success(ctx, resp)
 	{{else}}if err := {{ .LogicPackageName}}.{{ .LogicName}}.{{ .LogicFuncName}}(ctx{{if .PathParam}}, id{{end}}); err != nil {
-		gina.FailWithMessage(ctx, err.Error())
+		gina.Error(ctx, err)
 		return
 	}
 
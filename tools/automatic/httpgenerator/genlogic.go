@@ -33,6 +33,7 @@ const logicContentTemplate = `
 // @Summary {{ .Summary }}
 func (self *{{.LogicName}}) {{.FuncName}}(ctx context.Context,{{if .PathParam}} {{.PathParam}} int64,{{end}}{{if .RequestType}} params *{{.TypesPackageName}}.{{.RequestType}}{{end}}) ({{if .ResponseType}} resp {{if not (hasPrefix .ResponseType "[]")}}*{{.TypesPackageName}}.{{end}}{{.ResponseType}},{{end}} err error) {
     // TODO implement
+    // 业务错误返回 ginaerror.New(ginaerror.NotData) 或 ginaerror.Wrap(err, ginaerror.ServerError), 其他错误会返回系统错误
 
     return
 }
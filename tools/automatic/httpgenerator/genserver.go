@@ -16,6 +16,9 @@ const serverContentTemplate = `
 package {{.PackageName}}
 
 import (
+	"context"
+	"time"
+
 	"github.com/soryetong/greasyx/gina"
 	"github.com/soryetong/greasyx/modules/httpmodule"
 	"{{ .RouterPackagePath}}"
@@ -36,18 +39,25 @@ func (self *{{ .ServerName}}) OnStart() (err error) {
 	// 添加回调函数
 	self.httpModule.OnStop(self.exitCallback())
 
-	{{if .HasViper}} self.httpModule.Init(self, viper.GetString("App.Addr"), 5, router.InitRouter()) {{ else }}
-	self.httpModule.Init(self, "{{ .ServerAddr}}", 5, router.InitRouter()) {{end}}
+	// 停机时最多等待处理中的请求 5s, 同时受 App.ShutdownTimeout 的限制, http.Server 的超时参数从 App.Server 中读取
+	{{if .HasViper}} self.httpModule.Init(self, viper.GetString("App.Addr"), 5*time.Second, router.InitRouter()) {{ else }}
+	self.httpModule.Init(self, "{{ .ServerAddr}}", 5*time.Second, router.InitRouter()) {{end}}
 	err = self.httpModule.Start()
 
 	return
 }
 
+// 服务管理器收到退出信号后调用, 等待处理中的请求完成
+func (self *{{ .ServerName}}) OnStop(ctx context.Context) error {
+	return self.httpModule.Stop(ctx)
+}
+
 // TODO 添加回调函数, 无逻辑可直接删除这个方法
 func (self *{{ .ServerName}}) exitCallback() *httpmodule.CallbackMap {
-	callback := httpmodule.NewStopCallbackMap()
-	callback.Append("exit", func() {
-		gina.Log.Info("这是程序退出后的回调函数, 执行你想要执行的逻辑, 无逻辑可以直接删除这段代码")
+	callback := httpmodule.NewCallbackMap()
+	callback.Add("exit", httpmodule.PhaseAfterDrain, func(ctx context.Context) error {
+		gina.Log.Info("这是处理中的请求全部完成后的回调函数, 执行你想要执行的逻辑, 无逻辑可以直接删除这段代码")
+		return nil
 	})
 	
 	return callback